@@ -0,0 +1,372 @@
+package slopher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+type CassetteMode int
+
+const (
+	// Pass traffic through to Slack and record it
+	CassetteRecord CassetteMode = iota
+	// Serve recorded traffic without touching the network
+	CassetteReplay
+)
+
+// CassetteInteraction is one recorded HTTP exchange.
+type CassetteInteraction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+	// Set instead of Body when the body isn't text (e.g. a downloaded
+	// image). It's kept byte for byte, since scrubbing would corrupt it.
+	RawBody []byte `json:"raw_body,omitempty"`
+}
+
+// CassetteFrame is one recorded websocket message.
+type CassetteFrame struct {
+	// Which websocket connection (in the order they were opened)
+	Conn int `json:"conn"`
+	// "recv" for frames from Slack, "send" for frames from us
+	Dir  string `json:"dir"`
+	Type int    `json:"type"`
+	Data string `json:"data"`
+	// Set instead of Data for binary frames, like RawBody
+	RawData []byte `json:"raw_data,omitempty"`
+}
+
+// Cassette records the HTTP exchanges and websocket frames of a session
+// to a file, and replays them back later. Tokens are scrubbed from the
+// JSON and text that's recorded; binary bodies are kept as they were.
+// Attach it to a Client before creating an RTMProcessor:
+//
+//	cassette := NewCassetteRecorder("session.json")
+//	cassette.Attach(cli)
+//	...
+//	cassette.Save()
+//
+// and later:
+//
+//	cassette, err := LoadCassette("session.json")
+//	cassette.Attach(cli)
+type Cassette struct {
+	Interactions []*CassetteInteraction `json:"interactions"`
+	Frames       []*CassetteFrame       `json:"frames"`
+
+	mutex     sync.Mutex
+	mode      CassetteMode
+	path      string
+	transport http.RoundTripper
+	dialer    WSDialer
+	redactor  *redactor
+	// Replay position
+	used   []bool
+	nconns int
+}
+
+// Only these response headers are worth keeping.
+var cassetteHeaders = []string{"Content-Type", "Retry-After"}
+
+func NewCassetteRecorder(path string) *Cassette {
+	return &Cassette{
+		Interactions: make([]*CassetteInteraction, 0),
+		Frames:       make([]*CassetteFrame, 0),
+		mode:         CassetteRecord,
+		path:         path,
+		redactor:     newRedactor(),
+	}
+}
+
+// LoadCassette loads a recorded cassette for replay.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	self := &Cassette{
+		mode:     CassetteReplay,
+		path:     path,
+		redactor: newRedactor(),
+	}
+	if err := json.Unmarshal(data, self); err != nil {
+		return nil, fmt.Errorf("Couldn't decode cassette %s: %s", path, err)
+	}
+	self.used = make([]bool, len(self.Interactions))
+
+	return self, nil
+}
+
+func (self *Cassette) Mode() CassetteMode {
+	return self.mode
+}
+
+// Attach routes the Client's HTTP traffic, and the websocket
+// connections of RTMProcessors created from it, through the cassette.
+func (self *Cassette) Attach(cli *Client) {
+	self.transport = cli.client.Transport
+	self.dialer = cli.wsDialer
+	cli.SetTransport(self)
+	cli.SetWSDialer(self)
+}
+
+// Save writes what's been recorded to the cassette's file.
+func (self *Cassette) Save() error {
+	if self.mode != CassetteRecord {
+		return errors.New("Cassette isn't recording")
+	}
+
+	self.mutex.Lock()
+	data, err := json.MarshalIndent(self, "", "  ")
+	self.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(self.path, data, 0600)
+}
+
+func (self *Cassette) scrubURL(u *url.URL) string {
+	scrubbed := *u
+	query := scrubbed.Query()
+	for k, v := range query {
+		if self.redactor.isField(k) {
+			query[k] = []string{redactedValue}
+		} else {
+			for i := range v {
+				v[i] = tokenRegexp.ReplaceAllString(v[i], redactedValue)
+			}
+		}
+	}
+	scrubbed.RawQuery = query.Encode()
+	return scrubbed.String()
+}
+
+func (self *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	if self.mode == CassetteReplay {
+		return self.replay(req)
+	}
+
+	resp, err := self.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := &CassetteInteraction{
+		Method: req.Method,
+		URL:    self.scrubURL(req.URL),
+		Status: resp.StatusCode,
+		Header: make(http.Header),
+	}
+	if isTextBody(resp.Header.Get("Content-Type"), body) {
+		interaction.Body = self.redactor.redactBody(body)
+	} else {
+		interaction.RawBody = body
+	}
+	for _, hdr := range cassetteHeaders {
+		if v := resp.Header.Get(hdr); v != "" {
+			interaction.Header.Set(hdr, v)
+		}
+	}
+
+	self.mutex.Lock()
+	self.Interactions = append(self.Interactions, interaction)
+	self.mutex.Unlock()
+
+	return resp, nil
+}
+
+// Only JSON and text bodies are scrubbed of tokens; anything else would
+// be corrupted by it.
+func isTextBody(content_type string, body []byte) bool {
+	media, _, _ := mime.ParseMediaType(content_type)
+	switch {
+	case media == "application/json", strings.HasPrefix(media, "text/"),
+		media == "":
+		return utf8.Valid(body)
+	}
+	return false
+}
+
+// Serves the first unused interaction with the same method and path.
+func (self *Cassette) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i, interaction := range self.Interactions {
+		if self.used[i] || interaction.Method != req.Method {
+			continue
+		}
+		u, err := url.Parse(interaction.URL)
+		if err != nil || u.Path != req.URL.Path {
+			continue
+		}
+
+		self.used[i] = true
+
+		body := interaction.RawBody
+		if body == nil {
+			body = []byte(interaction.Body)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Cassette %s has no more %s %s interactions",
+		self.path, req.Method, req.URL.Path)
+}
+
+func (self *Cassette) Dial(url string, hdr http.Header) (WSConn, error) {
+	self.mutex.Lock()
+	conn_id := self.nconns
+	self.nconns++
+	self.mutex.Unlock()
+
+	if self.mode == CassetteReplay {
+		return newReplayWSConn(self, conn_id), nil
+	}
+
+	conn, err := self.dialer.Dial(url, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return &recordWSConn{WSConn: conn, cassette: self, id: conn_id}, nil
+}
+
+func (self *Cassette) recordFrame(conn_id int, dir string, msgtype int, data []byte) {
+	frame := &CassetteFrame{
+		Conn: conn_id,
+		Dir:  dir,
+		Type: msgtype,
+	}
+	if msgtype == websocket.TextMessage {
+		frame.Data = self.redactor.redactBody(data)
+	} else {
+		frame.RawData = data
+	}
+
+	self.mutex.Lock()
+	self.Frames = append(self.Frames, frame)
+	self.mutex.Unlock()
+}
+
+type recordWSConn struct {
+	WSConn
+	cassette *Cassette
+	id       int
+}
+
+func (self *recordWSConn) ReadMessage() (int, []byte, error) {
+	msgtype, data, err := self.WSConn.ReadMessage()
+	if err == nil {
+		self.cassette.recordFrame(self.id, "recv", msgtype, data)
+	}
+	return msgtype, data, err
+}
+
+func (self *recordWSConn) WriteMessage(msgtype int, data []byte) error {
+	err := self.WSConn.WriteMessage(msgtype, data)
+	if err == nil {
+		self.cassette.recordFrame(self.id, "send", msgtype, data)
+	}
+	return err
+}
+
+// Plays back the frames received on one recorded connection. Once
+// they're used up, reads block until the connection is closed, like an
+// idle connection would.
+type replayWSConn struct {
+	frames []*CassetteFrame
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newReplayWSConn(cassette *Cassette, conn_id int) *replayWSConn {
+	self := &replayWSConn{closed: make(chan struct{})}
+
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+
+	for _, frame := range cassette.Frames {
+		if frame.Conn == conn_id && frame.Dir == "recv" {
+			self.frames = append(self.frames, frame)
+		}
+	}
+	return self
+}
+
+func (self *replayWSConn) ReadMessage() (int, []byte, error) {
+	if len(self.frames) > 0 {
+		select {
+		case <-self.closed:
+		default:
+			frame := self.frames[0]
+			self.frames = self.frames[1:]
+			if frame.RawData != nil {
+				return frame.Type, frame.RawData, nil
+			}
+			return frame.Type, []byte(frame.Data), nil
+		}
+	}
+
+	<-self.closed
+	return 0, nil, &websocket.CloseError{Code: websocket.CloseNormalClosure}
+}
+
+func (self *replayWSConn) WriteMessage(msgtype int, data []byte) error {
+	select {
+	case <-self.closed:
+		return websocket.ErrCloseSent
+	default:
+		return nil
+	}
+}
+
+func (self *replayWSConn) WriteControl(msgtype int, data []byte, deadline time.Time) error {
+	if msgtype == websocket.CloseMessage {
+		self.Close()
+	}
+	return nil
+}
+
+func (self *replayWSConn) Close() error {
+	self.once.Do(func() { close(self.closed) })
+	return nil
+}
@@ -3,12 +3,15 @@ package slopher
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -22,11 +25,20 @@ var rtmProcessorKey contextKeyType = 1
 var rtmStateManagerKey contextKeyType = 2
 
 type Client struct {
-	transport *http.Transport
-	client    *http.Client
-	Uri       string
+	client   *http.Client
+	wsDialer WSDialer
+	Uri      string
+	// Bot token, also used for methods that don't need another kind
 	AuthToken string
-	log       *log.Logger
+	// User and app-level tokens, for methods that require them
+	UserToken string
+	AppToken  string
+	// Retries for transient failures. nil disables retrying.
+	RetryPolicy *RetryPolicy
+	log         Logger
+	redactor    *redactor
+	limiter     *rateLimiter
+	middleware  []Middleware
 }
 
 type APIArgs map[string]string
@@ -48,25 +60,95 @@ func (self *rawJSON) GetRaw() []byte {
 	return self.raw
 }
 
+type ResponseMetadata struct {
+	NextCursor string   `json:"next_cursor,omitempty"`
+	Messages   []string `json:"messages,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
 type baseAPIResponse struct {
 	rawJSON
-	Ok bool `json:"ok"`
+	Ok               bool              `json:"ok"`
+	Error            string            `json:"error,omitempty"`
+	Warning          string            `json:"warning,omitempty"`
+	ResponseMetadata *ResponseMetadata `json:"response_metadata,omitempty"`
 }
 
+func (self *baseAPIResponse) slackError(method string, status int) *SlackError {
+	if self.Ok {
+		return nil
+	}
+	serr := &SlackError{
+		Method:     method,
+		Code:       self.Error,
+		Warning:    self.Warning,
+		StatusCode: status,
+	}
+	if self.ResponseMetadata != nil {
+		serr.Messages = self.ResponseMetadata.Messages
+	}
+	if serr.Code == "" {
+		serr.Code = "unknown_error"
+	}
+	return serr
+}
+
+type apiResponse interface {
+	rawJSONSupporter
+	slackError(method string, status int) *SlackError
+}
+
+// NewClient returns a Client for the Web API at uri (DEFAULT_URI if
+// empty). logger may be nil, in which case nothing is logged; otherwise
+// everything down to LogDebug is written to it. Use SetLogger for other
+// loggers or levels.
 func NewClient(uri string, auth_token string, logger *log.Logger) *Client {
 	if uri == "" {
 		uri = DEFAULT_URI
 	}
-	tr := &http.Transport{}
+	var l Logger = NopLogger()
+	if logger != nil {
+		l = NewStdLogger(logger, LogDebug)
+	}
 	return &Client{
-		transport: tr,
-		client:    &http.Client{Transport: tr},
-		Uri:       uri,
-		AuthToken: auth_token,
-		log:       logger,
+		client:      &http.Client{Transport: &http.Transport{}},
+		wsDialer:    defaultWSDialer{},
+		Uri:         uri,
+		AuthToken:   auth_token,
+		RetryPolicy: DefaultRetryPolicy(),
+		log:         l,
+		redactor:    newRedactor(),
+		limiter:     newRateLimiter(),
 	}
 }
 
+func (self *Client) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger()
+	}
+	self.log = logger
+}
+
+// RedactFields adds arg (and JSON response and RTM frame) fields whose
+// values, including objects and arrays, should never be logged. Tokens
+// and file contents are always redacted. It's safe to call while the
+// Client is in use.
+func (self *Client) RedactFields(fields ...string) {
+	self.redactor.addFields(fields...)
+}
+
+// SetTransport replaces the http.RoundTripper used for API calls,
+// uploads and downloads.
+func (self *Client) SetTransport(transport http.RoundTripper) {
+	self.client.Transport = transport
+}
+
+// SetWSDialer replaces how RTMProcessors created with this Client open
+// their websocket connections.
+func (self *Client) SetWSDialer(dialer WSDialer) {
+	self.wsDialer = dialer
+}
+
 func (self *Client) NewContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, clientKey, self)
 }
@@ -76,6 +158,15 @@ func ClientFromContext(ctx context.Context) (*Client, bool) {
 	return u, ok
 }
 
+// For the convenience methods on Place, Message, etc.
+func clientFromContext(ctx context.Context) (*Client, error) {
+	cli, ok := ClientFromContext(ctx)
+	if !ok {
+		return nil, errors.New("No Client found in context")
+	}
+	return cli, nil
+}
+
 type RTMStartResponse struct {
 	baseAPIResponse
 
@@ -90,6 +181,14 @@ type RTMStartResponse struct {
 	IMs      []*IM      `json:"ims"`
 	Groups   []*Group   `json:"groups"`
 	Team     *Team      `json:"team"`
+
+	Subteams *RTMStartSubteams `json:"subteams,omitempty"`
+}
+
+type RTMStartSubteams struct {
+	// IDs of the groups we're in
+	Self []string     `json:"self"`
+	All  []*UserGroup `json:"all"`
 }
 
 func (self *Client) RTMStart(ctx context.Context) (*RTMStartResponse, error) {
@@ -108,15 +207,57 @@ type JoinChannelResponse struct {
 	Channel *Channel `json:"channel"`
 }
 
+// JoinChannel joins a public channel by name (with or without the "#").
+// The name is looked up in the StateManager in ctx, if any, and otherwise
+// with conversations.list. The channel is joined with conversations.join.
 func (self *Client) JoinChannel(ctx context.Context, name string) (*JoinChannelResponse, error) {
-	resp := &JoinChannelResponse{}
-	apiargs := APIArgs{"name": name}
+	name = strings.TrimPrefix(name, "#")
+
+	var channel_id string
+
+	if state_mgr, ok := StateManagerFromContext(ctx); ok {
+		if sm, ok := state_mgr.(*StateManager); ok {
+			place := sm.FindPlaceByName("#" + name)
+			if place != nil && place.Channel != nil && !place.Channel.IsArchived {
+				channel_id = place.ID
+			}
+		}
+	}
+
+	// Large pages, as this may have to look through every channel
+	pages := self.ConversationsListPages(APIArgs{
+		"types":            "public_channel",
+		"exclude_archived": "true",
+		"limit":            "1000",
+	})
+	for channel_id == "" && pages.Next(ctx) {
+		for _, conv := range pages.Page().(*ConversationsListResponse).Channels {
+			if conv.Name == name {
+				channel_id = conv.ID
+				break
+			}
+		}
+	}
+	if err := pages.Err(); err != nil {
+		return nil, err
+	}
+	if channel_id == "" {
+		return nil, &SlackError{
+			Method: "conversations.join",
+			Code:   "channel_not_found",
+		}
+	}
 
-	err := self.apiCall(ctx, "channels.join", apiargs, resp)
+	conv_resp, err := self.ConversationsJoin(ctx, channel_id)
 	if err != nil {
 		return nil, err
 	}
 
+	resp := &JoinChannelResponse{baseAPIResponse: conv_resp.baseAPIResponse}
+	if conv_resp.Channel != nil {
+		resp.Channel = conv_resp.Channel.ToChannel()
+	}
+
 	return resp, nil
 }
 
@@ -124,11 +265,13 @@ type LeaveChannelResponse struct {
 	baseAPIResponse
 }
 
+// LeaveChannel leaves a channel. It calls conversations.leave, so it
+// works for private channels too.
 func (self *Client) LeaveChannel(ctx context.Context, id string) (*LeaveChannelResponse, error) {
 	resp := &LeaveChannelResponse{}
 	apiargs := APIArgs{"channel": id}
 
-	err := self.apiCall(ctx, "channels.leave", apiargs, resp)
+	err := self.apiCall(ctx, "conversations.leave", apiargs, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -167,28 +310,35 @@ func (self *Client) PostChatMessage(ctx context.Context, args APIArgs, attachmen
 	return resp, nil
 }
 
-type UploadFileResponse struct {
-	baseAPIResponse
-
-	File *SharedFile `json:"file"`
-	/* Returns other keys on error */
+// PostMessageRequest is a chat.postMessage request, sent as JSON so
+// Blocks and Attachments don't need to be encoded by hand.
+type PostMessageRequest struct {
+	Channel     string       `json:"channel"`
+	Text        string       `json:"text,omitempty"`
+	Blocks      interface{}  `json:"blocks,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	ThreadTS       string `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool   `json:"reply_broadcast,omitempty"`
+
+	AsUser    bool   `json:"as_user,omitempty"`
+	Username  string `json:"username,omitempty"`
+	IconEmoji string `json:"icon_emoji,omitempty"`
+	IconURL   string `json:"icon_url,omitempty"`
+
+	Parse     string `json:"parse,omitempty"`
+	LinkNames bool   `json:"link_names,omitempty"`
+	// Pointers so we can tell if they were set, as Slack defaults them
+	// to true
+	Mrkdwn      *bool `json:"mrkdwn,omitempty"`
+	UnfurlLinks *bool `json:"unfurl_links,omitempty"`
+	UnfurlMedia *bool `json:"unfurl_media,omitempty"`
 }
 
-func (self *Client) UploadFile(ctx context.Context, filename, content, filetype, title string, channels []string) (*UploadFileResponse, error) {
-	resp := &UploadFileResponse{}
-
-	args := APIArgs{
-		"_filename":      filename,
-		"_file_contents": content,
-		"title":          title,
-		"filetype":       filetype,
-	}
-
-	if channels != nil {
-		args["channels"] = strings.Join(channels, ",")
-	}
+func (self *Client) PostMessage(ctx context.Context, req *PostMessageRequest) (*PostChatMessageResponse, error) {
+	resp := &PostChatMessageResponse{}
 
-	err := self.apiCall(ctx, "files.upload", args, resp)
+	err := self.apiCallRequest(ctx, "chat.postMessage", req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -196,21 +346,51 @@ func (self *Client) UploadFile(ctx context.Context, filename, content, filetype,
 	return resp, nil
 }
 
+type UploadFileResponse struct {
+	baseAPIResponse
+
+	File *SharedFile `json:"file"`
+	/* Returns other keys on error */
+}
+
+func (self *Client) UploadFile(ctx context.Context, filename, content, filetype, title string, channels []string) (*UploadFileResponse, error) {
+	return self.UploadFileReader(ctx, strings.NewReader(content), &UploadFileOptions{
+		Filename: filename,
+		FileType: filetype,
+		Title:    title,
+		Channels: channels,
+		Size:     int64(len(content)),
+	})
+}
+
 type IMListResponse struct {
 	baseAPIResponse
 
-	IMs []Channel `json:"ims"`
+	IMs []*IM `json:"ims"`
 }
 
+// IMList returns all of the IMs, following pagination. It calls
+// conversations.list with types=im.
 func (self *Client) IMList(ctx context.Context) (*IMListResponse, error) {
-	resp := &IMListResponse{}
-
-	err := self.apiCall(ctx, "im.list", nil, resp)
+	resp, err := self.IMListPages(nil).Collect(ctx, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	return resp, nil
+	convs := resp.(*ConversationsListResponse)
+
+	ims := &IMListResponse{baseAPIResponse: convs.baseAPIResponse}
+	for _, conv := range convs.Channels {
+		ims.IMs = append(ims.IMs, conv.ToIM())
+	}
+
+	return ims, nil
+}
+
+// IMListPages is ConversationsListPages with types=im, so its pages are
+// *ConversationsListResponse.
+func (self *Client) IMListPages(args APIArgs) *Paginator {
+	return self.ConversationsListPages(withArgs(args, "types", "im"))
 }
 
 type ChatDeleteResponse struct {
@@ -223,12 +403,15 @@ type ChatDeleteResponse struct {
 func (self *Client) ChatDelete(ctx context.Context, channel_id, ts string) (*ChatDeleteResponse, error) {
 	resp := &ChatDeleteResponse{}
 
-	args := APIArgs{
-		"channel": channel_id,
-		"ts":      ts,
+	req := &struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}{
+		Channel: channel_id,
+		TS:      ts,
 	}
 
-	err := self.apiCall(ctx, "chat.delete", args, resp)
+	err := self.apiCallRequest(ctx, "chat.delete", req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -237,90 +420,306 @@ func (self *Client) ChatDelete(ctx context.Context, channel_id, ts string) (*Cha
 }
 
 // Private methods
-func (self *Client) apiCall(ctx context.Context, method string, args APIArgs, apiresp rawJSONSupporter) error {
-	full_uri := self.Uri + fmt.Sprintf("/%s", method)
-
-	self.log.Printf("apiCall(%s) sending: %+v\n", method, args)
+func (self *Client) apiCall(ctx context.Context, method string, args APIArgs, apiresp apiResponse) error {
+	return self.apiDo(ctx, &APIRequest{Method: method, Args: args}, apiresp)
+}
 
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
+// Like apiCall, but also sends file as the multipart "file" field.
+func (self *Client) apiUpload(ctx context.Context, method string, args APIArgs, file *fileUpload, apiresp apiResponse) error {
+	req := &APIRequest{
+		Method:   method,
+		Args:     args,
+		Filename: file.filename,
+		file:     file,
+	}
+	return self.apiDo(ctx, req, apiresp)
+}
 
-	if filename, ok := args["_filename"]; ok {
-		contents := args["_file_contents"]
-		fw, err := w.CreateFormFile("file", filename)
+// Calls method with a typed request struct. It's sent as a JSON body if
+// the method accepts one, otherwise its fields are sent as form args.
+func (self *Client) apiCallRequest(ctx context.Context, method string, body interface{}, apiresp apiResponse) error {
+	if !lookupAPIMethod(method).json {
+		args, err := structToArgs(body)
 		if err != nil {
 			return err
 		}
+		return self.apiCall(ctx, method, args, apiresp)
+	}
 
-		if _, err := fw.Write([]byte(contents)); err != nil {
-			return err
-		}
-		delete(args, "_filename")
-		delete(args, "_file_contents")
+	return self.apiDo(ctx, &APIRequest{Method: method, Body: body}, apiresp)
+}
+
+// Runs req through the middleware chain and decodes the response into
+// apiresp.
+func (self *Client) apiDo(ctx context.Context, req *APIRequest, apiresp apiResponse) error {
+	body, err := self.handler()(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, apiresp); err != nil {
+		return err
 	}
 
-	for k, v := range args {
-		ff, err := w.CreateFormField(k)
+	apiresp.SetRaw(body)
+
+	if serr := apiresp.slackError(req.Method, 0); serr != nil {
+		return serr
+	}
+	return nil
+}
+
+// The innermost Handler, which actually sends req to Slack.
+func (self *Client) sendAPIRequest(ctx context.Context, req *APIRequest) ([]byte, error) {
+	var channel string
+	var body_fn func() (io.Reader, string, error)
+
+	if req.Body != nil {
+		payload, err := json.Marshal(req.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if _, err := ff.Write([]byte(v)); err != nil {
-			return err
+
+		// Only needed for rate limiting and logging
+		fields := &struct {
+			Channel string `json:"channel"`
+		}{}
+		json.Unmarshal(payload, fields)
+		channel = fields.Channel
+
+		self.log.Log(LogDebug, "API request", "method", req.Method,
+			"channel", channel, "body", self.redactor.redactBody(payload))
+
+		body_fn = func() (io.Reader, string, error) {
+			return bytes.NewReader(payload), "application/json; charset=utf-8", nil
+		}
+	} else {
+		channel = req.Args["channel"]
+
+		self.log.Log(LogDebug, "API request", "method", req.Method,
+			"channel", channel, "args", self.redactor.redactArgs(req.Args))
+
+		body_fn = func() (io.Reader, string, error) {
+			return self.multipartBody(req.Args, req.file)
 		}
 	}
 
-	// Add token
-	ff, err := w.CreateFormField("token")
+	return self.apiSend(ctx, req.Method, channel, body_fn)
+}
+
+// Sends the body from body_fn to method, retrying per RetryPolicy.
+func (self *Client) apiSend(ctx context.Context, method string, channel string, body_fn func() (io.Reader, string, error)) ([]byte, error) {
+	meth := lookupAPIMethod(method)
+	limit_key := meth.rateLimitKey(method, channel)
+
+	token, err := self.tokenFor(method, meth.token)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if _, err := ff.Write([]byte(self.AuthToken)); err != nil {
-		return err
+
+	for attempt := 1; ; attempt++ {
+		body, err := self.apiAttempt(ctx, method, meth, limit_key, token, body_fn)
+		if attempt > 1 && meth.alreadyDone != "" && IsSlackError(err, meth.alreadyDone) {
+			self.log.Log(LogInfo, "API call worked on an earlier attempt",
+				"method", method, "attempt", attempt, "error", err)
+			return []byte(`{"ok": true}`), nil
+		}
+		if err == nil || ctx.Err() != nil ||
+			!self.RetryPolicy.shouldRetry(method, attempt, err) {
+			return body, err
+		}
+
+		delay := self.RetryPolicy.backoff(attempt)
+		self.log.Log(LogWarn, "API call failed, will retry", "method", method,
+			"attempt", attempt, "delay", delay, "error", err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	w.Close()
+// Returns a multipart/form-data body with args and file (if not nil).
+// The file is streamed through a pipe rather than buffered.
+func (self *Client) multipartBody(args APIArgs, file *fileUpload) (io.Reader, string, error) {
+	writeFields := func(w *multipart.Writer) error {
+		for k, v := range args {
+			if err := w.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-	req, err := http.NewRequest("POST", full_uri, &b)
+	if file == nil {
+		var b bytes.Buffer
+		w := multipart.NewWriter(&b)
+		if err := writeFields(w); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		return &b, w.FormDataContentType(), nil
+	}
+
+	reader, err := file.open()
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	// Lets a retry stop this producer before the reader is rewound.
+	done := make(chan struct{})
+	file.wait = func() {
+		pr.CloseWithError(errUploadRestarted)
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+
+		err := writeFields(w)
+		if err == nil {
+			var fw io.Writer
+			fw, err = w.CreateFormFile("file", file.filename)
+			if err == nil {
+				_, err = io.Copy(fw, reader)
+			}
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, w.FormDataContentType(), nil
+}
+
+// A single attempt at an API call, which includes waiting out any rate
+// limiting. Returns the response body, along with a *SlackError if it
+// wasn't ok.
+func (self *Client) apiAttempt(ctx context.Context, method string, meth *apiMethod, limit_key string, token string, body_fn func() (io.Reader, string, error)) ([]byte, error) {
+	full_uri := self.Uri + fmt.Sprintf("/%s", method)
 
 	var body []byte
+	var status int
 
-	errch := make(chan error, 1)
+	for attempt := 1; ; attempt++ {
+		if err := self.limiter.wait(ctx, limit_key, meth.tier); err != nil {
+			return nil, err
+		}
 
-	go func() {
-		resp, err := self.client.Do(req)
+		payload, content_type, err := body_fn()
 		if err != nil {
-			errch <- err
-			return
+			return nil, err
 		}
 
-		defer resp.Body.Close()
+		req, err := http.NewRequest("POST", full_uri, payload)
+		if err != nil {
+			return nil, err
+		}
 
-		body, err = ioutil.ReadAll(resp.Body)
-		errch <- err
-	}()
+		req.Header.Set("Content-Type", content_type)
+		req.Header.Set("Authorization", "Bearer "+token)
 
-	select {
-	case <-ctx.Done():
-		self.transport.CancelRequest(req)
-		<-errch
-		return ctx.Err()
-	case err := <-errch:
+		start := time.Now()
+		var hdr http.Header
+		body, status, hdr, err = self.doRequest(ctx, req)
+		latency := time.Since(start)
 		if err != nil {
-			return err
+			self.log.Log(LogError, "API request failed", "method", method,
+				"latency", latency, "error", err)
+			return nil, err
+		}
+
+		self.log.Log(LogDebug, "API response", "method", method,
+			"status", status, "latency", latency,
+			"body", self.redactor.redactBody(body))
+
+		if status != http.StatusTooManyRequests {
+			break
+		}
+
+		retry_after := parseRetryAfter(hdr)
+		self.limiter.holdUntil(limit_key, meth.tier, time.Now().Add(retry_after))
+		if attempt > maxRateLimitRetries {
+			return body, &SlackError{
+				Method:     method,
+				Code:       "ratelimited",
+				StatusCode: status,
+				RetryAfter: retry_after,
+			}
 		}
+		self.log.Log(LogWarn, "API call rate limited", "method", method,
+			"key", limit_key, "retry_after", retry_after)
 	}
 
-	self.log.Printf("apiCall(%s) response: %s\n", method, body)
+	base := &baseAPIResponse{}
+	if err := json.Unmarshal(body, base); err != nil {
+		if status != http.StatusOK {
+			return body, &SlackError{
+				Method:     method,
+				Code:       "http_error",
+				StatusCode: status,
+			}
+		}
+		return body, err
+	}
 
-	if err := json.Unmarshal(body, apiresp); err != nil {
-		return err
+	if serr := base.slackError(method, status); serr != nil {
+		return body, serr
 	}
+	return body, nil
+}
 
-	apiresp.SetRaw(body)
-	return nil
+// Sends req, returning the response body, status and headers. The
+// request is aborted if ctx is done.
+func (self *Client) doRequest(ctx context.Context, req *http.Request) ([]byte, int, http.Header, error) {
+	resp, err := self.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, nil, ctx.Err()
+		}
+		return nil, 0, nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, nil, ctx.Err()
+		}
+		return nil, 0, nil, err
+	}
+
+	return body, resp.StatusCode, resp.Header, nil
+}
+
+// Converts a request struct to form args, using its JSON field names.
+// Strings are sent as-is; everything else is sent JSON encoded.
+func structToArgs(req interface{}) (APIArgs, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+
+	args := APIArgs{}
+	for k, raw := range fields {
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			args[k] = str
+		} else {
+			args[k] = string(raw)
+		}
+	}
+
+	return args, nil
 }
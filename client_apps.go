@@ -0,0 +1,23 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+type AppsConnectionsOpenResponse struct {
+	baseAPIResponse
+
+	// Socket Mode websocket URL
+	URL string `json:"url"`
+}
+
+// AppsConnectionsOpen returns a Socket Mode websocket URL. It needs the
+// Client's AppToken.
+func (self *Client) AppsConnectionsOpen(ctx context.Context) (*AppsConnectionsOpenResponse, error) {
+	resp := &AppsConnectionsOpenResponse{}
+
+	err := self.apiCall(ctx, "apps.connections.open", nil, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,57 @@
+package slopher
+
+import "fmt"
+
+// The kinds of token a Client can hold.
+type TokenType int
+
+const (
+	// Bot token (xoxb-). Methods that work with either a bot or a user
+	// token use this, falling back to the user token if there's no bot
+	// token.
+	TokenBot TokenType = iota
+	// User token (xoxp-), e.g. for search.*
+	TokenUser
+	// App-level token (xapp-)
+	TokenApp
+)
+
+var tokenTypeToName = map[TokenType]string{
+	TokenBot:  "bot",
+	TokenUser: "user",
+	TokenApp:  "app-level",
+}
+
+func (self TokenType) String() string {
+	if name, ok := tokenTypeToName[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenType(%d)", int(self))
+}
+
+// Returns the token to send for method, which requires token_type.
+func (self *Client) tokenFor(method string, token_type TokenType) (string, error) {
+	var token string
+
+	switch token_type {
+	case TokenBot:
+		token = self.AuthToken
+		if token == "" {
+			token = self.UserToken
+		}
+	case TokenUser:
+		token = self.UserToken
+	case TokenApp:
+		token = self.AppToken
+	}
+
+	if token == "" {
+		if method == "" {
+			return "", fmt.Errorf("No %s token configured", token_type)
+		}
+		return "", fmt.Errorf("%s requires the %s token, but none is configured",
+			method, token_type)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,100 @@
+package slopher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// Records the Authorization header and form fields of each request.
+type authRecorder struct {
+	auth  []string
+	forms []map[string][]string
+}
+
+func (self *authRecorder) transport() http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+		self.auth = append(self.auth, req.Header.Get("Authorization"))
+		self.forms = append(self.forms, req.MultipartForm.Value)
+		return textResponse(http.StatusOK, `{"ok": true, "url": "wss://example.test/link"}`), nil
+	})
+}
+
+func TestTokenForSendsBearerToken(t *testing.T) {
+	rec := &authRecorder{}
+	client := NewClient("https://slack.test/api", "xoxb-bot", nil)
+	client.UserToken = "xoxp-user"
+	client.AppToken = "xapp-app"
+	client.SetTransport(rec.transport())
+
+	ctx := context.Background()
+	if _, err := client.ConversationsInfo(ctx, "C1"); err != nil {
+		t.Fatalf("ConversationsInfo: %v", err)
+	}
+	if _, err := client.UsersSetPresence(ctx, "away"); err != nil {
+		t.Fatalf("UsersSetPresence: %v", err)
+	}
+	resp, err := client.AppsConnectionsOpen(ctx)
+	if err != nil {
+		t.Fatalf("AppsConnectionsOpen: %v", err)
+	}
+	if resp.URL != "wss://example.test/link" {
+		t.Errorf("URL = %q", resp.URL)
+	}
+
+	want := []string{"Bearer xoxb-bot", "Bearer xoxp-user", "Bearer xapp-app"}
+	for i, auth := range rec.auth {
+		if auth != want[i] {
+			t.Errorf("request %d: Authorization = %q, want %q", i, auth, want[i])
+		}
+		if _, ok := rec.forms[i]["token"]; ok {
+			t.Errorf("request %d: token sent as a form field", i)
+		}
+	}
+	if len(rec.auth) != len(want) {
+		t.Errorf("sent %d requests, want %d", len(rec.auth), len(want))
+	}
+}
+
+func TestTokenForFallsBackToUserToken(t *testing.T) {
+	rec := &authRecorder{}
+	client := NewClient("https://slack.test/api", "", nil)
+	client.UserToken = "xoxp-user"
+	client.SetTransport(rec.transport())
+
+	if _, err := client.ConversationsInfo(context.Background(), "C1"); err != nil {
+		t.Fatalf("ConversationsInfo: %v", err)
+	}
+	if len(rec.auth) != 1 || rec.auth[0] != "Bearer xoxp-user" {
+		t.Errorf("Authorization = %v", rec.auth)
+	}
+}
+
+func TestTokenForMissingToken(t *testing.T) {
+	rec := &authRecorder{}
+	client := NewClient("https://slack.test/api", "xoxb-bot", nil)
+	client.SetTransport(rec.transport())
+
+	ctx := context.Background()
+	_, err := client.UsersSetPresence(ctx, "away")
+	if err == nil || !strings.Contains(err.Error(), "users.setPresence requires the user token") {
+		t.Errorf("UsersSetPresence error = %v", err)
+	}
+	_, err = client.AppsConnectionsOpen(ctx)
+	if err == nil || !strings.Contains(err.Error(), "apps.connections.open requires the app-level token") {
+		t.Errorf("AppsConnectionsOpen error = %v", err)
+	}
+	if len(rec.auth) != 0 {
+		t.Errorf("sent %d requests without a token", len(rec.auth))
+	}
+
+	client = NewClient("https://slack.test/api", "", nil)
+	if _, err := client.tokenFor("", TokenBot); err == nil || err.Error() != "No bot token configured" {
+		t.Errorf("tokenFor error = %v", err)
+	}
+}
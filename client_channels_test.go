@@ -0,0 +1,124 @@
+package slopher_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestJoinChannelByName(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true})
+	srv.AddChannel(&slopher.Channel{ID: "C2", Name: "random", IsChannel: true})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	ctx := context.Background()
+
+	resp, err := client.JoinChannel(ctx, "#random")
+	if err != nil {
+		t.Fatalf("JoinChannel: %v", err)
+	}
+	if resp.Channel == nil || resp.Channel.ID != "C2" || !resp.Channel.IsMember {
+		t.Errorf("Channel = %+v", resp.Channel)
+	}
+
+	if _, err := client.JoinChannel(ctx, "nope"); !slopher.IsNotFound(err) {
+		t.Errorf("JoinChannel(nope) = %v, want channel_not_found", err)
+	}
+
+	for _, req := range srv.Requests() {
+		if req.Method == "conversations.list" && req.Args["limit"] != "1000" {
+			t.Errorf("conversations.list limit = %q", req.Args["limit"])
+		}
+	}
+}
+
+func TestJoinChannelUsesStateManager(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	state_mgr := slopher.GetDefaultStateManager()
+	ctx := client.NewContext(context.Background())
+	ctx = slopher.NewContextForStateManager(ctx, state_mgr)
+
+	rtm_resp, err := client.RTMStart(ctx)
+	if err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+	if err := state_mgr.RTMStart(ctx, rtm_resp); err != nil {
+		t.Fatalf("StateManager.RTMStart: %v", err)
+	}
+
+	resp, err := client.JoinChannel(ctx, "general")
+	if err != nil {
+		t.Fatalf("JoinChannel: %v", err)
+	}
+	if resp.Channel == nil || resp.Channel.ID != "C1" {
+		t.Errorf("Channel = %+v", resp.Channel)
+	}
+
+	for _, req := range srv.Requests() {
+		if req.Method == "conversations.list" {
+			t.Error("looked the channel up with conversations.list")
+		}
+	}
+}
+
+func TestLeaveChannel(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true, IsMember: true})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	ctx := context.Background()
+
+	if _, err := client.LeaveChannel(ctx, "C1"); err != nil {
+		t.Fatalf("LeaveChannel: %v", err)
+	}
+	info, err := client.ConversationsInfo(ctx, "C1")
+	if err != nil {
+		t.Fatalf("ConversationsInfo: %v", err)
+	}
+	if info.Channel.IsMember {
+		t.Error("still a member after LeaveChannel")
+	}
+
+	// Retired along with the rest of channels.*
+	req, _ := http.NewRequest("POST", srv.APIURL()+"/channels.leave", strings.NewReader("channel=C1"))
+	req.Header.Set("Authorization", "Bearer xoxb-test")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	http_resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("channels.leave: %v", err)
+	}
+	defer http_resp.Body.Close()
+	body, _ := ioutil.ReadAll(http_resp.Body)
+	if !strings.Contains(string(body), "unknown_method") {
+		t.Errorf("channels.leave answered %s", body)
+	}
+}
+
+func TestIMList(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true})
+	srv.AddIM(&slopher.IM{ID: "D1", UserID: "U1"})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+
+	resp, err := client.IMList(context.Background())
+	if err != nil {
+		t.Fatalf("IMList: %v", err)
+	}
+	if len(resp.IMs) != 1 || resp.IMs[0].ID != "D1" || resp.IMs[0].UserID != "U1" {
+		t.Errorf("IMs = %+v", resp.IMs)
+	}
+}
@@ -0,0 +1,318 @@
+package slopher
+
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+/*
+** chat.update
+ */
+
+// UpdateMessageRequest is a chat.update request. Text, Blocks and
+// Attachments replace those of the message.
+type UpdateMessageRequest struct {
+	Channel     string       `json:"channel"`
+	TS          string       `json:"ts"`
+	Text        string       `json:"text,omitempty"`
+	Blocks      interface{}  `json:"blocks,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	ReplyBroadcast bool   `json:"reply_broadcast,omitempty"`
+	AsUser         bool   `json:"as_user,omitempty"`
+	Parse          string `json:"parse,omitempty"`
+	LinkNames      bool   `json:"link_names,omitempty"`
+}
+
+type ChatUpdateResponse struct {
+	baseAPIResponse
+
+	ChannelID string   `json:"channel"`
+	TS        string   `json:"ts"`
+	Text      string   `json:"text"`
+	Message   *Message `json:"message"`
+}
+
+func (self *Client) ChatUpdate(ctx context.Context, req *UpdateMessageRequest) (*ChatUpdateResponse, error) {
+	resp := &ChatUpdateResponse{}
+
+	err := self.apiCallRequest(ctx, "chat.update", req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** chat.postEphemeral
+ */
+
+// PostEphemeralRequest is a chat.postEphemeral request: a message only
+// User will see, and only while they're connected.
+type PostEphemeralRequest struct {
+	Channel     string       `json:"channel"`
+	User        string       `json:"user"`
+	Text        string       `json:"text,omitempty"`
+	Blocks      interface{}  `json:"blocks,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	ThreadTS    string       `json:"thread_ts,omitempty"`
+
+	AsUser    bool   `json:"as_user,omitempty"`
+	Username  string `json:"username,omitempty"`
+	IconEmoji string `json:"icon_emoji,omitempty"`
+	IconURL   string `json:"icon_url,omitempty"`
+	Parse     string `json:"parse,omitempty"`
+	LinkNames bool   `json:"link_names,omitempty"`
+}
+
+type ChatPostEphemeralResponse struct {
+	baseAPIResponse
+
+	MessageTS string `json:"message_ts"`
+}
+
+func (self *Client) ChatPostEphemeral(ctx context.Context, req *PostEphemeralRequest) (*ChatPostEphemeralResponse, error) {
+	resp := &ChatPostEphemeralResponse{}
+
+	err := self.apiCallRequest(ctx, "chat.postEphemeral", req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** chat.getPermalink
+ */
+type ChatGetPermalinkResponse struct {
+	baseAPIResponse
+
+	ChannelID string `json:"channel"`
+	Permalink string `json:"permalink"`
+}
+
+func (self *Client) ChatGetPermalink(ctx context.Context, channel_id, ts string) (*ChatGetPermalinkResponse, error) {
+	resp := &ChatGetPermalinkResponse{}
+
+	args := APIArgs{
+		"channel":    channel_id,
+		"message_ts": ts,
+	}
+
+	err := self.apiCall(ctx, "chat.getPermalink", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** chat.meMessage
+ */
+type ChatMeMessageResponse struct {
+	baseAPIResponse
+
+	ChannelID string `json:"channel"`
+	TS        string `json:"ts"`
+}
+
+func (self *Client) ChatMeMessage(ctx context.Context, channel_id, text string) (*ChatMeMessageResponse, error) {
+	resp := &ChatMeMessageResponse{}
+
+	args := APIArgs{
+		"channel": channel_id,
+		"text":    text,
+	}
+
+	err := self.apiCall(ctx, "chat.meMessage", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** Scheduled messages
+ */
+type scheduleMessageRequest struct {
+	*PostMessageRequest
+
+	PostAt EpochTime `json:"post_at"`
+}
+
+type ChatScheduleMessageResponse struct {
+	baseAPIResponse
+
+	ChannelID          string    `json:"channel"`
+	ScheduledMessageID string    `json:"scheduled_message_id"`
+	PostAt             EpochTime `json:"post_at"`
+	Message            *Message  `json:"message"`
+}
+
+// ChatScheduleMessage has Slack post req at the given time, which must
+// be within 120 days.
+func (self *Client) ChatScheduleMessage(ctx context.Context, at time.Time, req *PostMessageRequest) (*ChatScheduleMessageResponse, error) {
+	resp := &ChatScheduleMessageResponse{}
+
+	sreq := &scheduleMessageRequest{
+		PostMessageRequest: req,
+		PostAt:             EpochTime(at),
+	}
+
+	err := self.apiCallRequest(ctx, "chat.scheduleMessage", sreq, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ScheduledMessage struct {
+	ID          string    `json:"id"`
+	ChannelID   string    `json:"channel_id"`
+	PostAt      EpochTime `json:"post_at"`
+	DateCreated EpochTime `json:"date_created"`
+	Text        string    `json:"text"`
+}
+
+type ChatScheduledMessagesListResponse struct {
+	baseAPIResponse
+
+	ScheduledMessages []*ScheduledMessage `json:"scheduled_messages"`
+}
+
+func (self *ChatScheduledMessagesListResponse) numItems() int {
+	return len(self.ScheduledMessages)
+}
+
+func (self *ChatScheduledMessagesListResponse) appendItems(page PagedResponse) {
+	self.ScheduledMessages = append(self.ScheduledMessages,
+		page.(*ChatScheduledMessagesListResponse).ScheduledMessages...)
+}
+
+func (self *ChatScheduledMessagesListResponse) truncateItems(n int) {
+	self.ScheduledMessages = self.ScheduledMessages[:n]
+}
+
+// ChatScheduledMessagesList returns all messages waiting to be posted,
+// following pagination. channel_id may be empty for all channels, and
+// oldest and latest zero for no limit.
+func (self *Client) ChatScheduledMessagesList(ctx context.Context, channel_id string, oldest, latest time.Time) (*ChatScheduledMessagesListResponse, error) {
+	args := APIArgs{}
+	if channel_id != "" {
+		args["channel"] = channel_id
+	}
+	if !oldest.IsZero() {
+		args["oldest"] = strconv.FormatInt(oldest.Unix(), 10)
+	}
+	if !latest.IsZero() {
+		args["latest"] = strconv.FormatInt(latest.Unix(), 10)
+	}
+
+	resp, err := self.ChatScheduledMessagesListPages(args).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ChatScheduledMessagesListResponse), nil
+}
+
+func (self *Client) ChatScheduledMessagesListPages(args APIArgs) *Paginator {
+	return self.newPaginator("chat.scheduledMessages.list", args, func() PagedResponse {
+		return &ChatScheduledMessagesListResponse{}
+	})
+}
+
+type ChatDeleteScheduledMessageResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) ChatDeleteScheduledMessage(ctx context.Context, channel_id, scheduled_message_id string) (*ChatDeleteScheduledMessageResponse, error) {
+	resp := &ChatDeleteScheduledMessageResponse{}
+
+	args := APIArgs{
+		"channel":              channel_id,
+		"scheduled_message_id": scheduled_message_id,
+	}
+
+	err := self.apiCall(ctx, "chat.deleteScheduledMessage", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** Convenience methods for received messages. These use the Client in
+** the context.
+ */
+
+// Update replaces the text of the message. Only messages we posted can
+// be updated.
+func (self *Message) Update(ctx context.Context, text string) (*ChatUpdateResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatUpdate(ctx, &UpdateMessageRequest{
+		Channel: self.ChannelID,
+		TS:      self.TS,
+		Text:    text,
+	})
+}
+
+func (self *Message) Delete(ctx context.Context) (*ChatDeleteResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatDelete(ctx, self.ChannelID, self.TS)
+}
+
+// Reply posts text in the message's channel, in its thread if it's in
+// one.
+func (self *Message) Reply(ctx context.Context, text string) (*PostChatMessageResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.PostMessage(ctx, &PostMessageRequest{
+		Channel:  self.ChannelID,
+		Text:     text,
+		ThreadTS: self.ThreadTS,
+	})
+}
+
+// ReplyEphemeral posts text in the message's channel that only the
+// message's sender will see.
+func (self *Message) ReplyEphemeral(ctx context.Context, text string) (*ChatPostEphemeralResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatPostEphemeral(ctx, &PostEphemeralRequest{
+		Channel:  self.ChannelID,
+		User:     self.UserID,
+		Text:     text,
+		ThreadTS: self.ThreadTS,
+	})
+}
+
+func (self *Message) Permalink(ctx context.Context) (string, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := cli.ChatGetPermalink(ctx, self.ChannelID, self.TS)
+	if err != nil {
+		return "", err
+	}
+	return resp.Permalink, nil
+}
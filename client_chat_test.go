@@ -0,0 +1,153 @@
+package slopher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestMessageAndPlaceHelpers(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddUser(&slopher.User{ID: "U1", Name: "alice"})
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true, IsMember: true})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	state_mgr := slopher.GetDefaultStateManager()
+	ctx := client.NewContext(context.Background())
+	ctx = slopher.NewContextForStateManager(ctx, state_mgr)
+
+	rtm_resp, err := client.RTMStart(ctx)
+	if err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+	state_mgr.RTMStart(ctx, rtm_resp)
+	place := state_mgr.FindPlace("C1")
+
+	posted, err := place.PostMessage(ctx, &slopher.PostMessageRequest{Text: "status: ok"})
+	if err != nil {
+		t.Fatalf("PostMessage: %v", err)
+	}
+	if _, err := place.UpdateMessage(ctx, posted.TS, "status: degraded"); err != nil {
+		t.Fatalf("UpdateMessage: %v", err)
+	}
+
+	// As if it came in over RTM
+	msg := &slopher.Message{BaseMessage: slopher.BaseMessage{
+		ChannelID: "C1",
+		UserID:    "U1",
+		TS:        "1500000000.000100",
+		ThreadTS:  "1500000000.000001",
+		Text:      "how's it going?",
+	}}
+	reply, err := msg.Reply(ctx, "fine")
+	if err != nil {
+		t.Fatalf("Reply: %v", err)
+	}
+	if _, err := place.MeMessage(ctx, "waves"); err != nil {
+		t.Fatalf("MeMessage: %v", err)
+	}
+
+	msgs := srv.PostedMessages()
+	if len(msgs) != 3 {
+		t.Fatalf("posted %d messages, want 3", len(msgs))
+	}
+	if msgs[0].TS != posted.TS || msgs[0].Text != "status: degraded" {
+		t.Errorf("updated message = %+v", msgs[0].BaseMessage)
+	}
+	if msgs[1].TS != reply.TS || msgs[1].Text != "fine" || msgs[1].ThreadTS != msg.ThreadTS {
+		t.Errorf("reply = %+v", msgs[1].BaseMessage)
+	}
+	if msgs[2].SubType != "me_message" || msgs[2].Text != "waves" {
+		t.Errorf("me message = %+v", msgs[2].BaseMessage)
+	}
+
+	// Not our message, so Update fails.
+	if _, err := msg.Update(ctx, "edited"); !slopher.IsSlackError(err, "message_not_found") {
+		t.Errorf("Update of someone else's message = %v", err)
+	}
+
+	ephemeral, err := msg.ReplyEphemeral(ctx, "only you can see this")
+	if err != nil {
+		t.Fatalf("ReplyEphemeral: %v", err)
+	}
+	if ephemeral.MessageTS == "" {
+		t.Error("no MessageTS")
+	}
+	reqs := srv.Requests()
+	last := reqs[len(reqs)-1]
+	if last.Method != "chat.postEphemeral" || last.Args["user"] != "U1" ||
+		last.Args["channel"] != "C1" || last.Args["thread_ts"] != msg.ThreadTS {
+		t.Errorf("chat.postEphemeral args = %v", last.Args)
+	}
+
+	link, err := msg.Permalink(ctx)
+	if err != nil {
+		t.Fatalf("Permalink: %v", err)
+	}
+	if want := "https://slacktest.slack.com/archives/C1/p1500000000000100"; link != want {
+		t.Errorf("Permalink = %q, want %q", link, want)
+	}
+	place_link, err := place.Permalink(ctx, msg.TS)
+	if err != nil || place_link != link {
+		t.Errorf("Place.Permalink = %q, %v", place_link, err)
+	}
+}
+
+func TestScheduledMessages(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	ctx := context.Background()
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	sched, err := client.ChatScheduleMessage(ctx, at, &slopher.PostMessageRequest{
+		Channel: "C1",
+		Text:    "standup in 5",
+	})
+	if err != nil {
+		t.Fatalf("ChatScheduleMessage: %v", err)
+	}
+	if sched.ScheduledMessageID == "" || !time.Time(sched.PostAt).Equal(at) ||
+		sched.Message == nil || sched.Message.Text != "standup in 5" {
+		t.Errorf("scheduled %+v", sched)
+	}
+	if _, err := client.ChatScheduleMessage(ctx, at.Add(time.Hour), &slopher.PostMessageRequest{
+		Channel: "C2",
+		Text:    "retro",
+	}); err != nil {
+		t.Fatalf("ChatScheduleMessage: %v", err)
+	}
+
+	_, err = client.ChatScheduleMessage(ctx, time.Now().Add(-time.Minute),
+		&slopher.PostMessageRequest{Channel: "C1", Text: "too late"})
+	if !slopher.IsSlackError(err, "time_in_past") {
+		t.Errorf("scheduling in the past = %v", err)
+	}
+
+	list, err := client.ChatScheduledMessagesList(ctx, "C1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ChatScheduledMessagesList: %v", err)
+	}
+	if len(list.ScheduledMessages) != 1 || list.ScheduledMessages[0].ID != sched.ScheduledMessageID ||
+		list.ScheduledMessages[0].Text != "standup in 5" {
+		t.Errorf("C1 scheduled messages = %+v", list.ScheduledMessages)
+	}
+
+	if _, err := client.ChatDeleteScheduledMessage(ctx, "C1", sched.ScheduledMessageID); err != nil {
+		t.Fatalf("ChatDeleteScheduledMessage: %v", err)
+	}
+	_, err = client.ChatDeleteScheduledMessage(ctx, "C1", sched.ScheduledMessageID)
+	if !slopher.IsSlackError(err, "invalid_scheduled_message_id") {
+		t.Errorf("deleting twice = %v", err)
+	}
+
+	left := srv.ScheduledMessages()
+	if len(left) != 1 || left[0].ChannelID != "C2" || left[0].Text != "retro" {
+		t.Errorf("left scheduled = %+v", left)
+	}
+}
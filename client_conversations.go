@@ -0,0 +1,429 @@
+package slopher
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Conversation is the unified channel/private channel/MPIM/IM model used
+// by the conversations.* methods. Use ToChannel, ToGroup or ToIM (per
+// IsIM etc.) to get the older types StateManager works with.
+type Conversation struct {
+	ID                 string          `json:"id"`
+	Name               string          `json:"name"`
+	NameNormalized     string          `json:"name_normalized"`
+	Created            EpochTime       `json:"created"`
+	Creator            string          `json:"creator"`
+	IsArchived         bool            `json:"is_archived"`
+	IsChannel          bool            `json:"is_channel"`
+	IsGeneral          bool            `json:"is_general"`
+	IsGroup            bool            `json:"is_group"`
+	IsIM               bool            `json:"is_im"`
+	IsMember           bool            `json:"is_member"`
+	IsMpIM             bool            `json:"is_mpim"`
+	IsOpen             bool            `json:"is_open"`
+	IsPrivate          bool            `json:"is_private"`
+	IsShared           bool            `json:"is_shared"`
+	IsExtShared        bool            `json:"is_ext_shared"`
+	IsOrgShared        bool            `json:"is_org_shared"`
+	LastRead           string          `json:"last_read"`
+	Latest             *Message        `json:"latest,omitempty"`
+	Members            []string        `json:"members,omitempty"`
+	NumMembers         int             `json:"num_members"`
+	Purpose            *ChannelPurpose `json:"purpose,omitempty"`
+	Topic              *ChannelTopic   `json:"topic,omitempty"`
+	UnreadCount        uint            `json:"unread_count"`
+	UnreadCountDisplay uint            `json:"unread_count_display"`
+	// For IMs, the other user
+	UserID string `json:"user,omitempty"`
+}
+
+// IsPublicChannel returns true for public channels (as opposed to
+// private channels, MPIMs and IMs).
+func (self *Conversation) IsPublicChannel() bool {
+	return !self.IsIM && !self.IsMpIM && !self.IsPrivate && !self.IsGroup
+}
+
+func (self *Conversation) ToChannel() *Channel {
+	return &Channel{
+		Created:            self.Created,
+		Creator:            self.Creator,
+		ID:                 self.ID,
+		IsArchived:         self.IsArchived,
+		IsChannel:          true,
+		IsGeneral:          self.IsGeneral,
+		IsMember:           self.IsMember,
+		LastRead:           self.LastRead,
+		Latest:             self.Latest,
+		Members:            self.Members,
+		Name:               self.Name,
+		Purpose:            self.Purpose,
+		Topic:              self.Topic,
+		UnreadCount:        self.UnreadCount,
+		UnreadCountDisplay: self.UnreadCountDisplay,
+	}
+}
+
+// ToGroup converts private channels and MPIMs, which the older API
+// called groups.
+func (self *Conversation) ToGroup() *Group {
+	return &Group{
+		Created:            self.Created,
+		Creator:            self.Creator,
+		ID:                 self.ID,
+		IsArchived:         self.IsArchived,
+		IsGroup:            true,
+		IsOpen:             self.IsOpen,
+		LastRead:           self.LastRead,
+		Latest:             self.Latest,
+		Members:            self.Members,
+		Name:               self.Name,
+		Purpose:            self.Purpose,
+		Topic:              self.Topic,
+		UnreadCount:        self.UnreadCount,
+		UnreadCountDisplay: self.UnreadCountDisplay,
+	}
+}
+
+func (self *Conversation) ToIM() *IM {
+	return &IM{
+		Created:            self.Created,
+		ID:                 self.ID,
+		IsIM:               true,
+		IsOpen:             self.IsOpen,
+		LastRead:           self.LastRead,
+		Latest:             self.Latest,
+		UnreadCount:        self.UnreadCount,
+		UnreadCountDisplay: self.UnreadCountDisplay,
+		UserID:             self.UserID,
+	}
+}
+
+// ConversationResponse is returned by the conversations.* methods that
+// return a single conversation.
+type ConversationResponse struct {
+	baseAPIResponse
+
+	Channel *Conversation `json:"channel"`
+}
+
+/*
+** conversations.list
+ */
+type ConversationsListResponse struct {
+	baseAPIResponse
+
+	Channels []*Conversation `json:"channels"`
+}
+
+func (self *ConversationsListResponse) numItems() int {
+	return len(self.Channels)
+}
+
+func (self *ConversationsListResponse) appendItems(page PagedResponse) {
+	self.Channels = append(self.Channels, page.(*ConversationsListResponse).Channels...)
+}
+
+func (self *ConversationsListResponse) truncateItems(n int) {
+	self.Channels = self.Channels[:n]
+}
+
+// ConversationsList returns all conversations, following pagination.
+// args may include "types" (e.g. "public_channel,private_channel,mpim,im")
+// and "exclude_archived".
+func (self *Client) ConversationsList(ctx context.Context, args APIArgs) (*ConversationsListResponse, error) {
+	resp, err := self.ConversationsListPages(args).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ConversationsListResponse), nil
+}
+
+func (self *Client) ConversationsListPages(args APIArgs) *Paginator {
+	return self.newPaginator("conversations.list", args, func() PagedResponse {
+		return &ConversationsListResponse{}
+	})
+}
+
+/*
+** conversations.info
+ */
+func (self *Client) ConversationsInfo(ctx context.Context, channel_id string) (*ConversationResponse, error) {
+	resp := &ConversationResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "conversations.info", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** conversations.history and conversations.replies
+ */
+type ConversationsHistoryResponse struct {
+	baseAPIResponse
+
+	Messages []*Message `json:"messages"`
+	HasMore  bool       `json:"has_more"`
+	PinCount int        `json:"pin_count"`
+}
+
+func (self *ConversationsHistoryResponse) numItems() int {
+	return len(self.Messages)
+}
+
+func (self *ConversationsHistoryResponse) appendItems(page PagedResponse) {
+	self.Messages = append(self.Messages, page.(*ConversationsHistoryResponse).Messages...)
+}
+
+func (self *ConversationsHistoryResponse) truncateItems(n int) {
+	self.Messages = self.Messages[:n]
+}
+
+// ConversationsHistory returns one page of a conversation's messages.
+// args may include "limit", "oldest", "latest", "inclusive" and
+// "cursor". Use ConversationsHistoryPages to walk all of them.
+func (self *Client) ConversationsHistory(ctx context.Context, channel_id string, args APIArgs) (*ConversationsHistoryResponse, error) {
+	pager := self.ConversationsHistoryPages(channel_id, args)
+	if !pager.Next(ctx) {
+		return nil, pager.Err()
+	}
+
+	return pager.Page().(*ConversationsHistoryResponse), nil
+}
+
+func (self *Client) ConversationsHistoryPages(channel_id string, args APIArgs) *Paginator {
+	return self.newPaginator("conversations.history", withArgs(args, "channel", channel_id), func() PagedResponse {
+		return &ConversationsHistoryResponse{}
+	})
+}
+
+// ConversationsReplies returns one page of the thread started by ts.
+func (self *Client) ConversationsReplies(ctx context.Context, channel_id, ts string, args APIArgs) (*ConversationsHistoryResponse, error) {
+	pager := self.ConversationsRepliesPages(channel_id, ts, args)
+	if !pager.Next(ctx) {
+		return nil, pager.Err()
+	}
+
+	return pager.Page().(*ConversationsHistoryResponse), nil
+}
+
+func (self *Client) ConversationsRepliesPages(channel_id, ts string, args APIArgs) *Paginator {
+	args = withArgs(withArgs(args, "channel", channel_id), "ts", ts)
+	return self.newPaginator("conversations.replies", args, func() PagedResponse {
+		return &ConversationsHistoryResponse{}
+	})
+}
+
+/*
+** conversations.members
+ */
+type ConversationsMembersResponse struct {
+	baseAPIResponse
+
+	Members []string `json:"members"`
+}
+
+func (self *ConversationsMembersResponse) numItems() int {
+	return len(self.Members)
+}
+
+func (self *ConversationsMembersResponse) appendItems(page PagedResponse) {
+	self.Members = append(self.Members, page.(*ConversationsMembersResponse).Members...)
+}
+
+func (self *ConversationsMembersResponse) truncateItems(n int) {
+	self.Members = self.Members[:n]
+}
+
+// ConversationsMembers returns the IDs of all members of a conversation.
+func (self *Client) ConversationsMembers(ctx context.Context, channel_id string) (*ConversationsMembersResponse, error) {
+	resp, err := self.ConversationsMembersPages(channel_id, nil).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ConversationsMembersResponse), nil
+}
+
+func (self *Client) ConversationsMembersPages(channel_id string, args APIArgs) *Paginator {
+	return self.newPaginator("conversations.members", withArgs(args, "channel", channel_id), func() PagedResponse {
+		return &ConversationsMembersResponse{}
+	})
+}
+
+/*
+** Creating, joining and leaving
+ */
+func (self *Client) ConversationsCreate(ctx context.Context, name string, is_private bool) (*ConversationResponse, error) {
+	resp := &ConversationResponse{}
+	args := APIArgs{"name": name}
+
+	if is_private {
+		args["is_private"] = "true"
+	}
+
+	err := self.apiCall(ctx, "conversations.create", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) ConversationsJoin(ctx context.Context, channel_id string) (*ConversationResponse, error) {
+	return self.conversationCall(ctx, "conversations.join", APIArgs{"channel": channel_id})
+}
+
+type ConversationsLeaveResponse struct {
+	baseAPIResponse
+
+	NotInChannel bool `json:"not_in_channel"`
+}
+
+func (self *Client) ConversationsLeave(ctx context.Context, channel_id string) (*ConversationsLeaveResponse, error) {
+	resp := &ConversationsLeaveResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "conversations.leave", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ConversationsOpenResponse struct {
+	baseAPIResponse
+
+	Channel     *Conversation `json:"channel"`
+	NoOp        bool          `json:"no_op"`
+	AlreadyOpen bool          `json:"already_open"`
+}
+
+// ConversationsOpen opens (or resumes) an IM with one user, or an MPIM
+// with several.
+func (self *Client) ConversationsOpen(ctx context.Context, user_ids []string) (*ConversationsOpenResponse, error) {
+	resp := &ConversationsOpenResponse{}
+	args := APIArgs{"users": strings.Join(user_ids, ",")}
+
+	err := self.apiCall(ctx, "conversations.open", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ConversationsCloseResponse struct {
+	baseAPIResponse
+
+	NoOp          bool `json:"no_op"`
+	AlreadyClosed bool `json:"already_closed"`
+}
+
+func (self *Client) ConversationsClose(ctx context.Context, channel_id string) (*ConversationsCloseResponse, error) {
+	resp := &ConversationsCloseResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "conversations.close", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** Managing conversations
+ */
+type ConversationsArchiveResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) ConversationsArchive(ctx context.Context, channel_id string) (*ConversationsArchiveResponse, error) {
+	resp := &ConversationsArchiveResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "conversations.archive", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) ConversationsUnarchive(ctx context.Context, channel_id string) (*ConversationsArchiveResponse, error) {
+	resp := &ConversationsArchiveResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "conversations.unarchive", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) ConversationsRename(ctx context.Context, channel_id, name string) (*ConversationResponse, error) {
+	args := APIArgs{"channel": channel_id, "name": name}
+	return self.conversationCall(ctx, "conversations.rename", args)
+}
+
+func (self *Client) ConversationsInvite(ctx context.Context, channel_id string, user_ids []string) (*ConversationResponse, error) {
+	args := APIArgs{"channel": channel_id, "users": strings.Join(user_ids, ",")}
+	return self.conversationCall(ctx, "conversations.invite", args)
+}
+
+type ConversationsKickResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) ConversationsKick(ctx context.Context, channel_id, user_id string) (*ConversationsKickResponse, error) {
+	resp := &ConversationsKickResponse{}
+	args := APIArgs{"channel": channel_id, "user": user_id}
+
+	err := self.apiCall(ctx, "conversations.kick", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) ConversationsSetTopic(ctx context.Context, channel_id, topic string) (*ConversationResponse, error) {
+	args := APIArgs{"channel": channel_id, "topic": topic}
+	return self.conversationCall(ctx, "conversations.setTopic", args)
+}
+
+func (self *Client) ConversationsSetPurpose(ctx context.Context, channel_id, purpose string) (*ConversationResponse, error) {
+	args := APIArgs{"channel": channel_id, "purpose": purpose}
+	return self.conversationCall(ctx, "conversations.setPurpose", args)
+}
+
+// For the methods that return ConversationResponse.
+func (self *Client) conversationCall(ctx context.Context, method string, args APIArgs) (*ConversationResponse, error) {
+	resp := &ConversationResponse{}
+
+	err := self.apiCall(ctx, method, args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Returns a copy of args with k set to v.
+func withArgs(args APIArgs, k, v string) APIArgs {
+	nargs := APIArgs{}
+	for ak, av := range args {
+		nargs[ak] = av
+	}
+	nargs[k] = v
+	return nargs
+}
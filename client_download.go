@@ -0,0 +1,193 @@
+package slopher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+var ErrDownloadTooLarge = errors.New("Download exceeds maximum size")
+var ErrUnexpectedContentType = errors.New("Download has unexpected content type")
+
+type DownloadOptions struct {
+	// Fail if the content is larger than this. 0 means no limit.
+	MaxSize int64
+	// If not empty, the Content-Type must match one of these. Entries
+	// ending in "/" match a whole type, e.g. "image/".
+	ContentTypes []string
+}
+
+// DownloadFile writes the content of a shared file to w, returning the
+// number of bytes written. The file is fetched from its private URL with
+// the Client's token.
+func (self *Client) DownloadFile(ctx context.Context, file *SharedFile, w io.Writer, opts *DownloadOptions) (int64, error) {
+	url := file.URLPrivateDownload
+	if url == "" {
+		url = file.URLPrivate
+	}
+	if url == "" {
+		return 0, fmt.Errorf("File %s has no private URL", file.ID)
+	}
+
+	// When the token isn't accepted, Slack answers with its HTML login
+	// page rather than an error, so don't accept HTML unless the file is.
+	reject_type := "text/html"
+	if strings.HasPrefix(file.MimeType, "text/html") {
+		reject_type = ""
+	}
+
+	return self.downloadURL(ctx, url, w, opts, reject_type)
+}
+
+// DownloadFileThumb writes one of the file's thumbnails to w. size is
+// the thumbnail size in pixels: 64, 80, 160, 360, 720 or 1024.
+func (self *Client) DownloadFileThumb(ctx context.Context, file *SharedFile, size int, w io.Writer, opts *DownloadOptions) (int64, error) {
+	var url string
+
+	switch size {
+	case 64:
+		url = file.Thumb64
+	case 80:
+		url = file.Thumb80
+	case 160:
+		url = file.Thumb160
+	case 360:
+		url = file.Thumb360
+	case 720:
+		url = file.Thumb720
+	case 1024:
+		url = file.Thumb1024
+	default:
+		return 0, fmt.Errorf("Unknown thumbnail size: %d", size)
+	}
+
+	if url == "" {
+		return 0, fmt.Errorf("File %s has no %d thumbnail", file.ID, size)
+	}
+
+	return self.downloadURL(ctx, url, w, opts, "text/html")
+}
+
+// Hosts (and their subdomains) downloads will send the token to.
+var slackFileHosts = []string{"slack.com", "slack-edge.com", "slack-files.com"}
+
+// DownloadURL writes the content at a (private) Slack URL to w. Like
+// the other downloads, it refuses URLs that aren't on Slack's file hosts.
+func (self *Client) DownloadURL(ctx context.Context, url string, w io.Writer, opts *DownloadOptions) (int64, error) {
+	return self.downloadURL(ctx, url, w, opts, "")
+}
+
+// Since the Client's token is sent along with downloads, the URL must be
+// https on one of Slack's file hosts (or on the Client's own API host).
+func (self *Client) checkDownloadURL(raw_url string) error {
+	u, err := url.Parse(raw_url)
+	if err != nil {
+		return err
+	}
+
+	if api, err := url.Parse(self.Uri); err == nil &&
+		u.Scheme == api.Scheme && u.Host == api.Host {
+		return nil
+	}
+
+	if u.Scheme == "https" {
+		host := strings.ToLower(u.Hostname())
+		for _, allowed := range slackFileHosts {
+			if host == allowed || strings.HasSuffix(host, "."+allowed) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("Refusing to download %s: not a Slack file URL", raw_url)
+}
+
+func (self *Client) downloadURL(ctx context.Context, url string, w io.Writer, opts *DownloadOptions, reject_type string) (int64, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+
+	if err := self.checkDownloadURL(url); err != nil {
+		return 0, err
+	}
+
+	token, err := self.tokenFor("", TokenBot)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	self.log.Log(LogDebug, "Downloading file", "url", url)
+
+	resp, err := self.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Download of %s failed: HTTP %d", url,
+			resp.StatusCode)
+	}
+
+	content_type, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if (reject_type != "" && content_type == reject_type) ||
+		!contentTypeAllowed(content_type, opts.ContentTypes) {
+		return 0, ErrUnexpectedContentType
+	}
+
+	if opts.MaxSize > 0 && resp.ContentLength > opts.MaxSize {
+		return 0, ErrDownloadTooLarge
+	}
+
+	var body io.Reader = resp.Body
+	if opts.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, opts.MaxSize)
+	}
+
+	n, err := io.Copy(w, body)
+	if err == nil && opts.MaxSize > 0 && n == opts.MaxSize {
+		// w has all it may get; anything left over means it was too
+		// large.
+		var extra [1]byte
+		if m, _ := io.ReadFull(resp.Body, extra[:]); m > 0 {
+			return n, ErrDownloadTooLarge
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		return n, err
+	}
+
+	return n, nil
+}
+
+func contentTypeAllowed(content_type string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == content_type ||
+			(strings.HasSuffix(a, "/") && strings.HasPrefix(content_type, a)) {
+			return true
+		}
+	}
+	return false
+}
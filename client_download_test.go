@@ -0,0 +1,88 @@
+package slopher
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestCheckDownloadURL(t *testing.T) {
+	client := NewClient("http://127.0.0.1:8080/api", "xoxb-test", nil)
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://files.slack.com/files-pri/T1-F1/download/a.png", true},
+		{"https://files-edu.slack.com/files-pri/T1-F1/a.png", true},
+		{"https://a.slack-edge.com/80588/img/a.png", true},
+		{"https://FILES.SLACK.COM/x", true},
+		{"http://127.0.0.1:8080/files/F1", true},
+		{"http://files.slack.com/files-pri/T1-F1/a.png", false},
+		{"https://files.slack.com.evil.example/x", false},
+		{"https://evilslack.com/x", false},
+		{"https://127.0.0.1:8080/files/F1", false},
+		{"file:///etc/passwd", false},
+	}
+
+	for _, test := range tests {
+		err := client.checkDownloadURL(test.url)
+		if (err == nil) != test.ok {
+			t.Errorf("checkDownloadURL(%q) = %v, want ok=%v", test.url, err, test.ok)
+		}
+	}
+}
+
+func TestDownloadFileRejectsOtherHosts(t *testing.T) {
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("token sent to %s", req.URL)
+		return textResponse(http.StatusOK, "data"), nil
+	}))
+
+	file := &SharedFile{ID: "F1", URLPrivate: "https://attacker.example/F1", Thumb64: "http://files.slack.com/t.png"}
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(context.Background(), file, &buf, nil); err == nil {
+		t.Error("DownloadFile succeeded for a non-Slack URL")
+	}
+	if _, err := client.DownloadFileThumb(context.Background(), file, 64, &buf, nil); err == nil {
+		t.Error("DownloadFileThumb succeeded for a non-https URL")
+	}
+}
+
+func TestDownloadMaxSize(t *testing.T) {
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("Authorization = %q", req.Header.Get("Authorization"))
+		}
+		// No Content-Length, so the size is only found out by reading.
+		resp := textResponse(http.StatusOK, "0123456789")
+		resp.ContentLength = -1
+		return resp, nil
+	}))
+
+	url := "https://files.slack.com/files-pri/T1-F1/data.bin"
+	tests := []struct {
+		max  int64
+		want string
+		err  error
+	}{
+		{0, "0123456789", nil},
+		{10, "0123456789", nil},
+		{11, "0123456789", nil},
+		{4, "0123", ErrDownloadTooLarge},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		n, err := client.DownloadURL(context.Background(), url, &buf, &DownloadOptions{MaxSize: test.max})
+		if err != test.err || buf.String() != test.want || n != int64(buf.Len()) {
+			t.Errorf("MaxSize %d: wrote %q (n=%d), err %v; want %q, %v",
+				test.max, buf.String(), n, err, test.want, test.err)
+		}
+	}
+}
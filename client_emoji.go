@@ -0,0 +1,87 @@
+package slopher
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// EmojiCatalog maps custom emoji names to image URLs, or to
+// "alias:<name>" for aliases.
+type EmojiCatalog map[string]string
+
+// Aliases can point at other aliases, but not forever.
+const maxEmojiAliasDepth = 10
+
+// Name without surrounding colons, e.g. "tada" for ":tada:".
+func emojiName(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, ":"), ":")
+}
+
+// Canonical follows aliases and returns the name of the emoji that name
+// refers to. This may be a built-in emoji that isn't in the catalog.
+func (self EmojiCatalog) Canonical(name string) string {
+	name = emojiName(name)
+	for i := 0; i < maxEmojiAliasDepth; i++ {
+		value, ok := self[name]
+		if !ok || !strings.HasPrefix(value, "alias:") {
+			break
+		}
+		name = strings.TrimPrefix(value, "alias:")
+	}
+	return name
+}
+
+// URL returns the image URL for a custom emoji, following aliases. It
+// returns false for built-in and unknown emoji.
+func (self EmojiCatalog) URL(name string) (string, bool) {
+	value, ok := self[self.Canonical(name)]
+	if !ok || strings.HasPrefix(value, "alias:") {
+		return "", false
+	}
+	return value, true
+}
+
+// Has returns true if name is a custom emoji or an alias.
+func (self EmojiCatalog) Has(name string) bool {
+	_, ok := self[emojiName(name)]
+	return ok
+}
+
+func (self EmojiCatalog) IsAlias(name string) bool {
+	return strings.HasPrefix(self[emojiName(name)], "alias:")
+}
+
+// Applies an emoji_changed event.
+func (self EmojiCatalog) update(msg *RTMEmojiChangedMessage) {
+	switch msg.SubType {
+	case "add":
+		self[msg.Name] = msg.Value
+	case "remove":
+		for _, name := range msg.Names {
+			delete(self, name)
+		}
+	case "rename":
+		delete(self, msg.OldName)
+		self[msg.NewName] = msg.Value
+	}
+}
+
+type EmojiListResponse struct {
+	baseAPIResponse
+
+	Emoji   EmojiCatalog `json:"emoji"`
+	CacheTS string       `json:"cache_ts"`
+}
+
+// EmojiList returns the team's custom emoji.
+func (self *Client) EmojiList(ctx context.Context) (*EmojiListResponse, error) {
+	resp := &EmojiListResponse{}
+
+	err := self.apiCall(ctx, "emoji.list", nil, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,108 @@
+package slopher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestEmojiCatalog(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddEmoji("party", "https://emoji.example/party.png")
+	srv.AddEmoji("tada2", "alias:party")
+	srv.AddEmoji("yay", "alias:tada2")
+	srv.AddEmoji("thumbs", "alias:+1")
+	srv.AddEmoji("loop1", "alias:loop2")
+	srv.AddEmoji("loop2", "alias:loop1")
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	state_mgr := slopher.GetDefaultStateManager()
+	ctx := client.NewContext(context.Background())
+	ctx = slopher.NewContextForStateManager(ctx, state_mgr)
+
+	rtm, err := slopher.NewRTMProcessor(ctx)
+	if err != nil {
+		t.Fatalf("NewRTMProcessor: %v", err)
+	}
+	if err := state_mgr.LoadEmoji(ctx); err != nil {
+		t.Fatalf("LoadEmoji: %v", err)
+	}
+	emoji := state_mgr.Emoji
+
+	canonical := map[string]string{
+		":yay:":   "party",
+		"tada2":   "party",
+		"party":   "party",
+		"thumbs":  "+1",
+		"smile":   "smile",
+		"unknown": "unknown",
+	}
+	for name, want := range canonical {
+		if got := emoji.Canonical(name); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", name, got, want)
+		}
+	}
+	// Doesn't loop forever
+	if got := emoji.Canonical("loop1"); got != "loop1" && got != "loop2" {
+		t.Errorf("Canonical(loop1) = %q", got)
+	}
+
+	if url, ok := emoji.URL("yay"); !ok || url != "https://emoji.example/party.png" {
+		t.Errorf("URL(yay) = %q, %v", url, ok)
+	}
+	if _, ok := emoji.URL("thumbs"); ok {
+		t.Error("URL(thumbs) found an alias of a built-in emoji")
+	}
+	if !emoji.Has(":yay:") || !emoji.IsAlias("yay") || emoji.IsAlias("party") || emoji.Has("smile") {
+		t.Error("Has/IsAlias are wrong")
+	}
+
+	changed := make(chan string, 3)
+	rtm.OnEmojiChanged(func(ctx context.Context, _msg slopher.RTMMessage) {
+		changed <- _msg.(*slopher.RTMEmojiChangedMessage).SubType
+	})
+	ctx = rtm.NewContext(ctx)
+	if err := rtm.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer rtm.Stop(ctx, false)
+	if err := srv.WaitForRTMConnection(5 * time.Second); err != nil {
+		t.Fatalf("WaitForRTMConnection: %v", err)
+	}
+
+	events := []map[string]interface{}{
+		{"type": "emoji_changed", "subtype": "add", "name": "shipit", "value": "alias:party"},
+		{"type": "emoji_changed", "subtype": "rename", "old_name": "party", "new_name": "partying",
+			"value": "https://emoji.example/party.png"},
+		{"type": "emoji_changed", "subtype": "remove", "names": []string{"tada2", "loop1"}},
+	}
+	for _, event := range events {
+		if err := srv.SendEvent(event); err != nil {
+			t.Fatalf("SendEvent: %v", err)
+		}
+		select {
+		case subtype := <-changed:
+			if subtype != event["subtype"] {
+				t.Errorf("got %q event, want %q", subtype, event["subtype"])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %v event", event["subtype"])
+		}
+	}
+
+	if !emoji.Has("shipit") || !emoji.Has("partying") || emoji.Has("party") ||
+		emoji.Has("tada2") || emoji.Has("loop1") {
+		t.Errorf("catalog after changes = %v", emoji)
+	}
+	// Aliases of a renamed emoji aren't rewritten.
+	if got := emoji.Canonical("shipit"); got != "party" {
+		t.Errorf("Canonical(shipit) = %q", got)
+	}
+	if url, ok := emoji.URL("partying"); !ok || url != "https://emoji.example/party.png" {
+		t.Errorf("URL(partying) = %q, %v", url, ok)
+	}
+}
@@ -0,0 +1,87 @@
+package slopher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SlackError is returned by every API method when Slack answers with
+// "ok": false (or with a non-JSON error response).
+type SlackError struct {
+	Method     string
+	Code       string
+	Warning    string
+	Messages   []string
+	StatusCode int
+	// Set when Slack asked us to back off (HTTP 429)
+	RetryAfter time.Duration
+}
+
+func (self *SlackError) Error() string {
+	s := fmt.Sprintf("slack: %s failed: %s", self.Method, self.Code)
+	if self.StatusCode != 0 && self.StatusCode != 200 {
+		s += fmt.Sprintf(" (HTTP %d)", self.StatusCode)
+	}
+	if len(self.Messages) > 0 {
+		s += ": " + strings.Join(self.Messages, "; ")
+	}
+	return s
+}
+
+var notFoundErrors = map[string]bool{
+	"channel_not_found": true,
+	"user_not_found":    true,
+	"users_not_found":   true,
+	"message_not_found": true,
+	"file_not_found":    true,
+	"bot_not_found":     true,
+	"not_found":         true,
+}
+
+var authErrors = map[string]bool{
+	"not_authed":             true,
+	"invalid_auth":           true,
+	"account_inactive":       true,
+	"token_revoked":          true,
+	"token_expired":          true,
+	"no_permission":          true,
+	"missing_scope":          true,
+	"not_allowed_token_type": true,
+	"ekm_access_denied":      true,
+}
+
+// AsSlackError returns the *SlackError in err, if there is one. Errors
+// wrapped by middleware (with %w) are unwrapped.
+func AsSlackError(err error) (*SlackError, bool) {
+	var serr *SlackError
+	ok := errors.As(err, &serr)
+	return serr, ok
+}
+
+// IsSlackError returns true if err is a *SlackError with the given code.
+func IsSlackError(err error, code string) bool {
+	serr, ok := AsSlackError(err)
+	return ok && serr.Code == code
+}
+
+// IsNotFound returns true if Slack reported that the referenced object
+// (channel, user, message, file, ...) does not exist.
+func IsNotFound(err error) bool {
+	serr, ok := AsSlackError(err)
+	return ok && notFoundErrors[serr.Code]
+}
+
+// IsAuthError returns true if Slack rejected the token or its scopes.
+func IsAuthError(err error) bool {
+	serr, ok := AsSlackError(err)
+	return ok && (authErrors[serr.Code] || serr.StatusCode == 401 ||
+		serr.StatusCode == 403)
+}
+
+// IsRateLimited returns true if the call gave up after being rate limited.
+func IsRateLimited(err error) bool {
+	serr, ok := AsSlackError(err)
+	return ok && (serr.Code == "ratelimited" || serr.StatusCode == 429)
+}
@@ -0,0 +1,39 @@
+package slopher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAsSlackErrorUnwraps(t *testing.T) {
+	serr := &SlackError{Method: "users.info", Code: "user_not_found"}
+	wrapped := fmt.Errorf("middleware: %w", serr)
+
+	got, ok := AsSlackError(wrapped)
+	if !ok || got != serr {
+		t.Fatalf("AsSlackError(wrapped) = %v, %v", got, ok)
+	}
+	if !IsNotFound(wrapped) {
+		t.Error("IsNotFound(wrapped) = false")
+	}
+	if !IsSlackError(wrapped, "user_not_found") {
+		t.Error("IsSlackError(wrapped) = false")
+	}
+
+	if _, ok := AsSlackError(errors.New("other")); ok {
+		t.Error("AsSlackError(other) = true")
+	}
+	if IsAuthError(nil) {
+		t.Error("IsAuthError(nil) = true")
+	}
+}
+
+func TestIsAuthErrorStatus(t *testing.T) {
+	if !IsAuthError(&SlackError{Code: "whatever", StatusCode: 403}) {
+		t.Error("403 isn't an auth error")
+	}
+	if !IsAuthError(&SlackError{Code: "invalid_auth", StatusCode: 200}) {
+		t.Error("invalid_auth isn't an auth error")
+	}
+}
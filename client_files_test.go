@@ -0,0 +1,36 @@
+package slopher_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestUploadThenDownloadFile(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	ctx := context.Background()
+	content := []byte{0x89, 'P', 'N', 'G', 0xff, 0xfe, 0x00, 0x01}
+
+	resp, err := client.UploadFileReader(ctx, bytes.NewReader(content),
+		&slopher.UploadFileOptions{Filename: "a.png", Channels: []string{"C1"}})
+	if err != nil {
+		t.Fatalf("UploadFileReader: %v", err)
+	}
+	if got, _ := srv.FileContent(resp.File.ID); !bytes.Equal(got, content) {
+		t.Errorf("server got %v", got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadFile(ctx, resp.File, &buf, nil); err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("downloaded %v", buf.Bytes())
+	}
+}
@@ -0,0 +1,217 @@
+package slopher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelToName = map[LogLevel]string{
+	LogDebug: "DEBUG",
+	LogInfo:  "INFO",
+	LogWarn:  "WARN",
+	LogError: "ERROR",
+}
+
+func (self LogLevel) String() string {
+	if name, ok := logLevelToName[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(self))
+}
+
+// Logger receives log messages along with alternating key/value pairs,
+// e.g.:
+//
+//	Log(LogDebug, "API response", "method", "chat.postMessage",
+//		"latency", latency)
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(LogLevel, string, ...interface{}) {}
+
+// NopLogger returns a Logger that discards everything. It's used when
+// no logger is given to NewClient.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type stdLogger struct {
+	log      *log.Logger
+	minLevel LogLevel
+}
+
+// NewStdLogger returns a Logger writing messages at minLevel and above
+// to a standard library *log.Logger as:
+//
+//	[DEBUG] API response method=chat.postMessage latency=120ms
+func NewStdLogger(logger *log.Logger, minLevel LogLevel) Logger {
+	return &stdLogger{log: logger, minLevel: minLevel}
+}
+
+func (self *stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	if level < self.minLevel {
+		return
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "[%s] %s", level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var val interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			val = keyvals[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], val)
+	}
+	self.log.Print(b.String())
+}
+
+const redactedValue = "[REDACTED]"
+
+// Anything that looks like a Slack token.
+var tokenRegexp = regexp.MustCompile(`\b(xox[abposre]|xapp)-[A-Za-z0-9-]+`)
+
+// Fields that are always redacted from logged args and responses.
+var defaultRedactedFields = []string{
+	"token",
+	"client_secret",
+	"refresh_token",
+}
+
+// redactor is shared by everything logging for a Client, so it's safe
+// to add fields while requests are running: they're never changed in
+// place, just replaced under the mutex.
+type redactor struct {
+	mutex  sync.Mutex
+	fields map[string]bool
+	// Matches "field": in JSON
+	keyExp *regexp.Regexp
+	// Matches "field": "string value" in JSON
+	stringExp *regexp.Regexp
+}
+
+func newRedactor() *redactor {
+	self := &redactor{fields: make(map[string]bool)}
+	self.addFields(defaultRedactedFields...)
+	return self
+}
+
+func (self *redactor) addFields(fields ...string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	all := make(map[string]bool, len(self.fields)+len(fields))
+	for field, _ := range self.fields {
+		all[field] = true
+	}
+	for _, field := range fields {
+		all[field] = true
+	}
+
+	quoted := make([]string, 0, len(all))
+	for field, _ := range all {
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	sort.Strings(quoted)
+
+	key := `"(` + strings.Join(quoted, "|") + `)"\s*:`
+	self.keyExp = regexp.MustCompile(key)
+	self.stringExp = regexp.MustCompile(key + `\s*"(?:[^"\\]|\\.)*"`)
+	self.fields = all
+}
+
+func (self *redactor) snapshot() (map[string]bool, *regexp.Regexp, *regexp.Regexp) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.fields, self.keyExp, self.stringExp
+}
+
+func (self *redactor) isField(field string) bool {
+	fields, _, _ := self.snapshot()
+	return fields[field]
+}
+
+// Returns a copy of args that's safe to log.
+func (self *redactor) redactArgs(args APIArgs) APIArgs {
+	fields, _, _ := self.snapshot()
+
+	redacted := make(APIArgs, len(args))
+	for k, v := range args {
+		if fields[k] {
+			v = redactedValue
+		} else {
+			v = tokenRegexp.ReplaceAllString(v, redactedValue)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// Returns a copy of a (JSON) body that's safe to log. Redacted fields
+// lose their whole value, whether it's a string, object or array.
+func (self *redactor) redactBody(body []byte) string {
+	fields, key_exp, string_exp := self.snapshot()
+
+	s := string(body)
+	if key_exp.MatchString(s) {
+		if redacted, err := redactJSON(body, fields); err == nil {
+			s = redacted
+		} else {
+			// Not JSON after all; get what we can.
+			s = string_exp.ReplaceAllString(s, `"$1":"`+redactedValue+`"`)
+		}
+	}
+	return tokenRegexp.ReplaceAllString(s, redactedValue)
+}
+
+func redactJSON(body []byte, fields map[string]bool) (string, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v, fields)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func redactValue(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range v {
+			if fields[k] {
+				v[k] = redactedValue
+			} else {
+				v[k] = redactValue(fv, fields)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redactValue(e, fields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package slopher
+
+// Per-method properties of the Slack Web API methods we call.
+type apiMethod struct {
+	tier RateLimitTier
+	// Which kind of token the method needs
+	token TokenType
+	// Safe to send again if we don't know whether the first one worked
+	idempotent bool
+	// Accepts an application/json body
+	json bool
+	// Error code meaning the call already had its effect, e.g.
+	// already_reacted. A retry getting it means an earlier attempt
+	// worked, so it counts as success.
+	alreadyDone string
+}
+
+var defaultAPIMethod = &apiMethod{
+	tier: Tier3,
+}
+
+var apiMethods = map[string]*apiMethod{
+	"rtm.start":        {tier: Tier1, idempotent: true},
+	"chat.postMessage": {tier: TierPost, json: true},
+	"chat.delete":      {tier: Tier3, json: true},
+	"files.upload":     {tier: Tier2},
+
+	"chat.update":        {tier: Tier3, idempotent: true, json: true},
+	"chat.postEphemeral": {tier: Tier4, json: true},
+	"chat.getPermalink":  {tier: Tier4, idempotent: true},
+	"chat.meMessage":     {tier: Tier3},
+
+	"chat.scheduleMessage":        {tier: Tier3, json: true},
+	"chat.scheduledMessages.list": {tier: Tier3, idempotent: true},
+	"chat.deleteScheduledMessage": {tier: Tier3, idempotent: true},
+
+	"conversations.list":       {tier: Tier2, idempotent: true},
+	"conversations.info":       {tier: Tier3, idempotent: true},
+	"conversations.history":    {tier: Tier3, idempotent: true},
+	"conversations.replies":    {tier: Tier3, idempotent: true},
+	"conversations.members":    {tier: Tier4, idempotent: true},
+	"conversations.create":     {tier: Tier2},
+	"conversations.join":       {tier: Tier3, idempotent: true},
+	"conversations.leave":      {tier: Tier3, idempotent: true},
+	"conversations.open":       {tier: Tier3, idempotent: true},
+	"conversations.close":      {tier: Tier2, idempotent: true},
+	"conversations.archive":    {tier: Tier2, idempotent: true},
+	"conversations.unarchive":  {tier: Tier2, idempotent: true},
+	"conversations.rename":     {tier: Tier2, idempotent: true},
+	"conversations.invite":     {tier: Tier3},
+	"conversations.kick":       {tier: Tier3},
+	"conversations.setTopic":   {tier: Tier2, idempotent: true},
+	"conversations.setPurpose": {tier: Tier2, idempotent: true},
+
+	"reactions.add":    {tier: Tier3, idempotent: true, alreadyDone: "already_reacted"},
+	"reactions.remove": {tier: Tier2, idempotent: true, alreadyDone: "no_reaction"},
+	"reactions.get":    {tier: Tier3, idempotent: true},
+	"reactions.list":   {tier: Tier2, idempotent: true},
+
+	"users.info":          {tier: Tier4, idempotent: true},
+	"users.list":          {tier: Tier2, idempotent: true},
+	"users.lookupByEmail": {tier: Tier3, idempotent: true},
+	"users.getPresence":   {tier: Tier3, idempotent: true},
+	"users.setPresence":   {tier: Tier2, idempotent: true, token: TokenUser},
+	"users.conversations": {tier: Tier3, idempotent: true},
+
+	"users.profile.get": {tier: Tier4, idempotent: true},
+	"users.profile.set": {tier: Tier3, idempotent: true, json: true, token: TokenUser},
+	"team.profile.get":  {tier: Tier3, idempotent: true},
+
+	"team.info":            {tier: Tier3, idempotent: true},
+	"team.accessLogs":      {tier: Tier2, idempotent: true, token: TokenUser},
+	"team.billableInfo":    {tier: Tier2, idempotent: true, token: TokenUser},
+	"team.integrationLogs": {tier: Tier2, idempotent: true, token: TokenUser},
+
+	"usergroups.list":         {tier: Tier2, idempotent: true},
+	"usergroups.create":       {tier: Tier2},
+	"usergroups.update":       {tier: Tier2, idempotent: true},
+	"usergroups.enable":       {tier: Tier2, idempotent: true},
+	"usergroups.disable":      {tier: Tier2, idempotent: true},
+	"usergroups.users.list":   {tier: Tier2, idempotent: true},
+	"usergroups.users.update": {tier: Tier2, idempotent: true},
+
+	"emoji.list": {tier: Tier2, idempotent: true},
+
+	"reminders.add":      {tier: Tier2, token: TokenUser},
+	"reminders.complete": {tier: Tier2, idempotent: true, token: TokenUser},
+	"reminders.delete":   {tier: Tier2, idempotent: true, token: TokenUser},
+	"reminders.info":     {tier: Tier2, idempotent: true, token: TokenUser},
+	"reminders.list":     {tier: Tier2, idempotent: true, token: TokenUser},
+
+	"pins.add":     {tier: Tier2, idempotent: true, alreadyDone: "already_pinned"},
+	"pins.remove":  {tier: Tier2, idempotent: true, alreadyDone: "no_pin"},
+	"pins.list":    {tier: Tier2, idempotent: true},
+	"stars.add":    {tier: Tier2, idempotent: true, token: TokenUser, alreadyDone: "already_starred"},
+	"stars.remove": {tier: Tier2, idempotent: true, token: TokenUser, alreadyDone: "not_starred"},
+	"stars.list":   {tier: Tier3, idempotent: true, token: TokenUser},
+
+	"files.getUploadURLExternal":   {tier: Tier4, idempotent: true},
+	"files.completeUploadExternal": {tier: Tier4, json: true},
+
+	"search.messages": {tier: Tier2, idempotent: true, token: TokenUser},
+	"search.files":    {tier: Tier2, idempotent: true, token: TokenUser},
+	"search.all":      {tier: Tier2, idempotent: true, token: TokenUser},
+
+	"apps.connections.open": {tier: Tier1, idempotent: true, token: TokenApp},
+}
+
+func lookupAPIMethod(method string) *apiMethod {
+	if meth, ok := apiMethods[method]; ok {
+		return meth
+	}
+	return defaultAPIMethod
+}
+
+// Tier limits are per method, except for posting, which Slack limits
+// per channel.
+func (self *apiMethod) rateLimitKey(method string, channel string) string {
+	if self.tier == TierPost {
+		return method + ":" + channel
+	}
+	return method
+}
@@ -0,0 +1,41 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+// APIRequest is an API call as seen by middleware. Middleware may modify
+// it before passing it on.
+type APIRequest struct {
+	Method string
+	// Form args. nil for JSON requests.
+	Args APIArgs
+	// Request struct that will be sent as JSON, for methods that accept
+	// it. nil for form requests.
+	Body interface{}
+	// Name of the file being uploaded, if any
+	Filename string
+
+	file *fileUpload
+}
+
+// Handler performs an API call, returning the raw JSON response. If the
+// response isn't ok, a *SlackError is returned along with it.
+type Handler func(ctx context.Context, req *APIRequest) ([]byte, error)
+
+// Middleware wraps a Handler. It can inspect or change the request, the
+// response and the error, or answer without calling next at all.
+type Middleware func(next Handler) Handler
+
+// Use adds middleware that every API call goes through. The first
+// middleware added is the outermost. File downloads and the second step
+// of UploadFilesExternal aren't API calls, so they bypass middleware.
+func (self *Client) Use(mw ...Middleware) {
+	self.middleware = append(self.middleware, mw...)
+}
+
+func (self *Client) handler() Handler {
+	handler := Handler(self.sendAPIRequest)
+	for i := len(self.middleware) - 1; i >= 0; i-- {
+		handler = self.middleware[i](handler)
+	}
+	return handler
+}
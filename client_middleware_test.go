@@ -0,0 +1,132 @@
+package slopher
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// Records the methods that go through it, tagged with name.
+type middlewareRecorder struct {
+	mutex sync.Mutex
+	calls []string
+}
+
+func (self *middlewareRecorder) middleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) ([]byte, error) {
+			self.mutex.Lock()
+			self.calls = append(self.calls, name+" "+req.Method)
+			self.mutex.Unlock()
+			return next(ctx, req)
+		}
+	}
+}
+
+func okTransport(calls *int) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*calls++
+		return textResponse(http.StatusOK, `{"ok": true, "url": "wss://example.test/ws", "file": {"id": "F1"}}`), nil
+	})
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var sent int
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(okTransport(&sent))
+
+	rec := &middlewareRecorder{}
+	client.Use(rec.middleware("outer"), rec.middleware("middle"))
+	client.Use(rec.middleware("inner"))
+
+	ctx := context.Background()
+	if _, err := client.RTMStart(ctx); err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+	if _, err := client.UploadFileReader(ctx, bytes.NewReader([]byte("data")),
+		&UploadFileOptions{Filename: "data.txt"}); err != nil {
+		t.Fatalf("UploadFileReader: %v", err)
+	}
+
+	want := []string{
+		"outer rtm.start", "middle rtm.start", "inner rtm.start",
+		"outer files.upload", "middle files.upload", "inner files.upload",
+	}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("calls = %v, want %v", rec.calls, want)
+	}
+	if sent != 2 {
+		t.Errorf("transport saw %d requests, want 2", sent)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	var sent int
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(okTransport(&sent))
+
+	var reached bool
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) ([]byte, error) {
+			if req.Method == "chat.postMessage" {
+				return []byte(`{"ok": false, "error": "channel_not_found"}`), nil
+			}
+			return []byte(`{"ok": true, "url": "wss://canned.test/ws"}`), nil
+		}
+	})
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) ([]byte, error) {
+			reached = true
+			return next(ctx, req)
+		}
+	})
+
+	ctx := context.Background()
+	resp, err := client.RTMStart(ctx)
+	if err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+	if resp.WSUrl != "wss://canned.test/ws" {
+		t.Errorf("WSUrl = %q", resp.WSUrl)
+	}
+
+	_, err = client.PostMessage(ctx, &PostMessageRequest{Channel: "C1", Text: "hi"})
+	serr, ok := AsSlackError(err)
+	if !ok || serr.Code != "channel_not_found" || serr.Method != "chat.postMessage" {
+		t.Errorf("PostMessage error = %v", err)
+	}
+
+	if reached || sent != 0 {
+		t.Errorf("canned response still reached inner middleware (%v) or transport (%d)", reached, sent)
+	}
+}
+
+func TestMiddlewareCanRewriteRequests(t *testing.T) {
+	var got string
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.ParseMultipartForm(1 << 20)
+		got = req.FormValue("channel")
+		return textResponse(http.StatusOK, `{"ok": true}`), nil
+	}))
+	client.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *APIRequest) ([]byte, error) {
+			if req.Args != nil {
+				req.Args["channel"] = strings.ToUpper(req.Args["channel"])
+			}
+			return next(ctx, req)
+		}
+	})
+
+	if _, err := client.ConversationsInfo(context.Background(), "c1"); err != nil {
+		t.Fatalf("ConversationsInfo: %v", err)
+	}
+	if got != "C1" {
+		t.Errorf("channel = %q, want C1", got)
+	}
+}
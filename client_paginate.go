@@ -0,0 +1,147 @@
+package slopher
+
+import (
+	"strconv"
+
+	"golang.org/x/net/context"
+)
+
+// Paging is the legacy page/count pagination info some methods return.
+type Paging struct {
+	Count int `json:"count"`
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+// PagedResponse is implemented by the responses of list-style methods.
+type PagedResponse interface {
+	apiResponse
+	NextCursor() string
+	pageInfo() *Paging
+	numItems() int
+	appendItems(page PagedResponse)
+	truncateItems(n int)
+}
+
+func (self *baseAPIResponse) NextCursor() string {
+	if self.ResponseMetadata == nil {
+		return ""
+	}
+	return self.ResponseMetadata.NextCursor
+}
+
+func (self *baseAPIResponse) pageInfo() *Paging {
+	return nil
+}
+
+// Paginator walks the pages of a list-style method, following
+// response_metadata.next_cursor or, for older methods, page/pages.
+//
+//	pager := cli.ConversationsListPages(APIArgs{"limit": "200"})
+//	for pager.Next(ctx) {
+//		resp := pager.Page().(*ConversationsListResponse)
+//		...
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+type Paginator struct {
+	client  *Client
+	method  string
+	args    APIArgs
+	newPage func() PagedResponse
+
+	cursor string
+	page   int
+	done   bool
+	resp   PagedResponse
+	err    error
+}
+
+func (self *Client) newPaginator(method string, args APIArgs, newPage func() PagedResponse) *Paginator {
+	return &Paginator{
+		client:  self,
+		method:  method,
+		args:    args,
+		newPage: newPage,
+	}
+}
+
+// Next fetches the next page, returning false when there are no more
+// pages or an error occurred.
+func (self *Paginator) Next(ctx context.Context) bool {
+	if self.done || self.err != nil {
+		return false
+	}
+
+	args := APIArgs{}
+	for k, v := range self.args {
+		args[k] = v
+	}
+	if self.cursor != "" {
+		args["cursor"] = self.cursor
+	} else if self.page > 0 {
+		args["page"] = strconv.Itoa(self.page)
+	}
+
+	resp := self.newPage()
+	if err := self.client.apiCall(ctx, self.method, args, resp); err != nil {
+		self.err = err
+		return false
+	}
+
+	self.resp = resp
+	self.cursor = resp.NextCursor()
+	self.done = true
+	if self.cursor != "" {
+		self.done = false
+	} else if paging := resp.pageInfo(); paging != nil && paging.Page < paging.Pages {
+		self.page = paging.Page + 1
+		self.done = false
+	}
+
+	return true
+}
+
+// Page returns the page fetched by the last successful call to Next.
+func (self *Paginator) Page() PagedResponse {
+	return self.resp
+}
+
+// Err returns the error that stopped Next, if any.
+func (self *Paginator) Err() error {
+	return self.err
+}
+
+// Collect fetches the remaining pages and returns the first of them with
+// the items of all the others appended. If limit > 0, at most limit items
+// are returned.
+func (self *Paginator) Collect(ctx context.Context, limit int) (PagedResponse, error) {
+	var all PagedResponse
+
+	for all == nil || limit <= 0 || all.numItems() < limit {
+		if !self.Next(ctx) {
+			break
+		}
+		if all == nil {
+			all = self.resp
+		} else {
+			all.appendItems(self.resp)
+		}
+	}
+
+	if self.err != nil {
+		return nil, self.err
+	}
+
+	if all == nil {
+		all = self.newPage()
+	}
+
+	if limit > 0 && all.numItems() > limit {
+		all.truncateItems(limit)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,152 @@
+package slopher
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// A Client whose transport answers conversations.list with cursors and
+// team.accessLogs with pages, recording the args of each call.
+func newPagingClient(t *testing.T) (*Client, *[]APIArgs) {
+	calls := &[]APIArgs{}
+
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.UserToken = "xoxp-test"
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+		args := APIArgs{}
+		for k := range req.MultipartForm.Value {
+			args[k] = req.FormValue(k)
+		}
+		*calls = append(*calls, args)
+
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/conversations.list"):
+			pages := map[string]string{
+				"":   `{"ok": true, "channels": [{"id": "C1"}, {"id": "C2"}], "response_metadata": {"next_cursor": "c2"}}`,
+				"c2": `{"ok": true, "channels": [{"id": "C3"}], "response_metadata": {"next_cursor": "c3"}}`,
+				"c3": `{"ok": true, "channels": [{"id": "C4"}], "response_metadata": {"next_cursor": ""}}`,
+			}
+			if body, ok := pages[args["cursor"]]; ok {
+				return textResponse(http.StatusOK, body), nil
+			}
+			return textResponse(http.StatusOK, `{"ok": false, "error": "invalid_cursor"}`), nil
+		case strings.HasSuffix(req.URL.Path, "/team.accessLogs"):
+			page, _ := strconv.Atoi(args["page"])
+			if page == 0 {
+				page = 1
+			}
+			return textResponse(http.StatusOK, fmt.Sprintf(
+				`{"ok": true, "logins": [{"user_id": "U%d"}], "paging": {"count": 1, "total": 3, "page": %d, "pages": 3}}`,
+				page, page)), nil
+		}
+		t.Errorf("unexpected call to %s", req.URL.Path)
+		return textResponse(http.StatusNotFound, ""), nil
+	}))
+
+	return client, calls
+}
+
+func conversationIDs(resp PagedResponse) string {
+	ids := []string{}
+	for _, conv := range resp.(*ConversationsListResponse).Channels {
+		ids = append(ids, conv.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestPaginatorCursor(t *testing.T) {
+	client, calls := newPagingClient(t)
+	args := APIArgs{"types": "public_channel"}
+
+	pager := client.ConversationsListPages(args)
+	pages := []string{}
+	for pager.Next(context.Background()) {
+		pages = append(pages, conversationIDs(pager.Page()))
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	if got := strings.Join(pages, "|"); got != "C1,C2|C3|C4" {
+		t.Errorf("pages = %s", got)
+	}
+	if len(*calls) != 3 || (*calls)[1]["cursor"] != "c2" || (*calls)[2]["cursor"] != "c3" {
+		t.Errorf("calls = %v", *calls)
+	}
+	if (*calls)[2]["types"] != "public_channel" {
+		t.Error("args weren't sent with every page")
+	}
+	if _, ok := args["cursor"]; ok {
+		t.Error("caller's args were modified")
+	}
+}
+
+func TestPaginatorCollect(t *testing.T) {
+	client, calls := newPagingClient(t)
+
+	resp, err := client.ConversationsListPages(nil).Collect(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if ids := conversationIDs(resp); ids != "C1,C2,C3,C4" {
+		t.Errorf("Collect(0) = %s", ids)
+	}
+
+	// Stops fetching once it has enough, then truncates.
+	*calls = nil
+	resp, err = client.ConversationsListPages(nil).Collect(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if ids := conversationIDs(resp); ids != "C1,C2,C3" {
+		t.Errorf("Collect(3) = %s", ids)
+	}
+	if len(*calls) != 2 {
+		t.Errorf("Collect(3) made %d calls, want 2", len(*calls))
+	}
+}
+
+func TestPaginatorPages(t *testing.T) {
+	client, calls := newPagingClient(t)
+
+	pager := client.TeamAccessLogsPages(nil)
+	users := []string{}
+	for pager.Next(context.Background()) {
+		for _, login := range pager.Page().(*TeamAccessLogsResponse).Logins {
+			users = append(users, login.UserID)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+
+	if got := strings.Join(users, ","); got != "U1,U2,U3" {
+		t.Errorf("users = %s", got)
+	}
+	if len(*calls) != 3 || (*calls)[0]["page"] != "" || (*calls)[2]["page"] != "3" {
+		t.Errorf("calls = %v", *calls)
+	}
+}
+
+func TestPaginatorError(t *testing.T) {
+	client, _ := newPagingClient(t)
+
+	pager := client.ConversationsListPages(APIArgs{"cursor": "bogus"})
+	if pager.Next(context.Background()) {
+		t.Fatal("Next succeeded")
+	}
+	if !IsSlackError(pager.Err(), "invalid_cursor") {
+		t.Errorf("Err = %v", pager.Err())
+	}
+	if pager.Next(context.Background()) {
+		t.Error("Next succeeded after an error")
+	}
+}
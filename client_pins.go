@@ -0,0 +1,169 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+/*
+** pins.*
+ */
+
+// PinnedItem is a message or file pinned to a channel.
+type PinnedItem struct {
+	Type      string      `json:"type"`
+	ChannelID string      `json:"channel"`
+	Created   EpochTime   `json:"created"`
+	CreatedBy string      `json:"created_by"`
+	Message   *Message    `json:"message,omitempty"`
+	File      *SharedFile `json:"file,omitempty"`
+}
+
+// Ref returns a reference to the pinned item, e.g. for PinsRemove.
+func (self *PinnedItem) Ref() *ItemRef {
+	ref := &ItemRef{Type: self.Type, ChannelID: self.ChannelID}
+	if self.Message != nil {
+		ref.TS = self.Message.TS
+	}
+	if self.File != nil {
+		ref.FileID = self.File.ID
+	}
+	return ref
+}
+
+type PinsAddResponse struct {
+	baseAPIResponse
+}
+
+// PinsAdd pins a message (or file) to the channel in item.
+func (self *Client) PinsAdd(ctx context.Context, item *ItemRef) (*PinsAddResponse, error) {
+	resp := &PinsAddResponse{}
+
+	err := self.apiCall(ctx, "pins.add", item.args(), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type PinsRemoveResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) PinsRemove(ctx context.Context, item *ItemRef) (*PinsRemoveResponse, error) {
+	resp := &PinsRemoveResponse{}
+
+	err := self.apiCall(ctx, "pins.remove", item.args(), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type PinsListResponse struct {
+	baseAPIResponse
+
+	Items []*PinnedItem `json:"items"`
+}
+
+func (self *Client) PinsList(ctx context.Context, channel_id string) (*PinsListResponse, error) {
+	resp := &PinsListResponse{}
+	args := APIArgs{"channel": channel_id}
+
+	err := self.apiCall(ctx, "pins.list", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Items don't always say which channel they're in.
+	for _, item := range resp.Items {
+		if item.ChannelID == "" {
+			item.ChannelID = channel_id
+		}
+	}
+
+	return resp, nil
+}
+
+/*
+** stars.*
+ */
+type StarredItem struct {
+	Type       string      `json:"type"`
+	ChannelID  string      `json:"channel,omitempty"`
+	DateCreate EpochTime   `json:"date_create"`
+	Message    *Message    `json:"message,omitempty"`
+	File       *SharedFile `json:"file,omitempty"`
+}
+
+type StarsAddResponse struct {
+	baseAPIResponse
+}
+
+// StarsAdd stars an item for the token's user. item may also refer to
+// just a channel. Stars need a user token.
+func (self *Client) StarsAdd(ctx context.Context, item *ItemRef) (*StarsAddResponse, error) {
+	resp := &StarsAddResponse{}
+
+	err := self.apiCall(ctx, "stars.add", item.args(), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type StarsRemoveResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) StarsRemove(ctx context.Context, item *ItemRef) (*StarsRemoveResponse, error) {
+	resp := &StarsRemoveResponse{}
+
+	err := self.apiCall(ctx, "stars.remove", item.args(), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type StarsListResponse struct {
+	baseAPIResponse
+
+	Items []*StarredItem `json:"items"`
+	// Older responses page this way instead of with cursors
+	Paging *Paging `json:"paging,omitempty"`
+}
+
+func (self *StarsListResponse) pageInfo() *Paging {
+	return self.Paging
+}
+
+func (self *StarsListResponse) numItems() int {
+	return len(self.Items)
+}
+
+func (self *StarsListResponse) appendItems(page PagedResponse) {
+	self.Items = append(self.Items, page.(*StarsListResponse).Items...)
+}
+
+func (self *StarsListResponse) truncateItems(n int) {
+	self.Items = self.Items[:n]
+}
+
+// StarsList returns all of the token user's starred items, following
+// pagination.
+func (self *Client) StarsList(ctx context.Context) (*StarsListResponse, error) {
+	resp, err := self.StarsListPages(nil).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*StarsListResponse), nil
+}
+
+func (self *Client) StarsListPages(args APIArgs) *Paginator {
+	return self.newPaginator("stars.list", args, func() PagedResponse {
+		return &StarsListResponse{}
+	})
+}
@@ -0,0 +1,148 @@
+package slopher
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+/*
+** users.profile.*
+ */
+type UsersProfileResponse struct {
+	baseAPIResponse
+
+	Profile *UserProfile `json:"profile"`
+}
+
+// UsersProfileGet returns a user's profile. An empty user_id means the
+// token's user.
+func (self *Client) UsersProfileGet(ctx context.Context, user_id string, include_labels bool) (*UsersProfileResponse, error) {
+	resp := &UsersProfileResponse{}
+	args := APIArgs{}
+
+	if user_id != "" {
+		args["user"] = user_id
+	}
+	if include_labels {
+		args["include_labels"] = "true"
+	}
+
+	err := self.apiCall(ctx, "users.profile.get", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type usersProfileSetRequest struct {
+	User    string                 `json:"user,omitempty"`
+	Profile map[string]interface{} `json:"profile,omitempty"`
+	Name    string                 `json:"name,omitempty"`
+	Value   string                 `json:"value,omitempty"`
+}
+
+// UsersProfileSet changes the given profile fields, keyed by their JSON
+// names (e.g. "display_name", or a custom field's ID), leaving the rest
+// alone. Changing other users' profiles needs an admin's user token.
+func (self *Client) UsersProfileSet(ctx context.Context, user_id string, profile map[string]interface{}) (*UsersProfileResponse, error) {
+	return self.usersProfileSet(ctx, &usersProfileSetRequest{
+		User:    user_id,
+		Profile: profile,
+	})
+}
+
+// UsersProfileSetField changes a single profile field.
+func (self *Client) UsersProfileSetField(ctx context.Context, user_id, name, value string) (*UsersProfileResponse, error) {
+	return self.usersProfileSet(ctx, &usersProfileSetRequest{
+		User:  user_id,
+		Name:  name,
+		Value: value,
+	})
+}
+
+// UsersProfileSetStatus sets a user's status. A zero expiration means
+// the status doesn't expire. Empty text and emoji clear the status.
+func (self *Client) UsersProfileSetStatus(ctx context.Context, user_id, text, emoji string, expiration time.Time) (*UsersProfileResponse, error) {
+	return self.UsersProfileSet(ctx, user_id, map[string]interface{}{
+		"status_text":       text,
+		"status_emoji":      emoji,
+		"status_expiration": EpochTime(expiration),
+	})
+}
+
+func (self *Client) usersProfileSet(ctx context.Context, req *usersProfileSetRequest) (*UsersProfileResponse, error) {
+	resp := &UsersProfileResponse{}
+
+	err := self.apiCallRequest(ctx, "users.profile.set", req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** team.profile.get
+ */
+
+// TeamProfileField describes one of the team's custom profile fields.
+type TeamProfileField struct {
+	ID             string   `json:"id"`
+	Ordering       int      `json:"ordering"`
+	Label          string   `json:"label"`
+	Hint           string   `json:"hint"`
+	Type           string   `json:"type"`
+	PossibleValues []string `json:"possible_values"`
+	IsHidden       bool     `json:"is_hidden"`
+	SectionID      string   `json:"section_id"`
+}
+
+type TeamProfileSection struct {
+	ID          string `json:"id"`
+	TeamID      string `json:"team_id"`
+	SectionType string `json:"section_type"`
+	Label       string `json:"label"`
+	Order       int    `json:"order"`
+	IsHidden    bool   `json:"is_hidden"`
+}
+
+type TeamProfile struct {
+	Fields   []*TeamProfileField   `json:"fields"`
+	Sections []*TeamProfileSection `json:"sections"`
+}
+
+// Field returns the field with the given ID, or nil.
+func (self *TeamProfile) Field(id string) *TeamProfileField {
+	for _, field := range self.Fields {
+		if field.ID == id {
+			return field
+		}
+	}
+	return nil
+}
+
+type TeamProfileGetResponse struct {
+	baseAPIResponse
+
+	Profile *TeamProfile `json:"profile"`
+}
+
+// TeamProfileGet returns the team's custom profile fields. visibility
+// may be "all", "visible" or "hidden", or empty for all.
+func (self *Client) TeamProfileGet(ctx context.Context, visibility string) (*TeamProfileGetResponse, error) {
+	resp := &TeamProfileGetResponse{}
+	args := APIArgs{}
+
+	if visibility != "" {
+		args["visibility"] = visibility
+	}
+
+	err := self.apiCall(ctx, "team.profile.get", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
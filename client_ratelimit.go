@@ -0,0 +1,127 @@
+package slopher
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Slack's published rate limit tiers. See
+// https://api.slack.com/docs/rate-limits
+type RateLimitTier int
+
+const (
+	Tier1 RateLimitTier = iota + 1
+	Tier2
+	Tier3
+	Tier4
+	// Special limit for posting messages: about 1 per second per channel
+	TierPost
+)
+
+// How many times a call that got HTTP 429 is retried before giving up.
+const maxRateLimitRetries = 3
+
+// If Slack doesn't send Retry-After with a 429, wait this long.
+const defaultRetryAfter = time.Second
+
+type rateLimit struct {
+	perMinute int
+	burst     int
+}
+
+var rateLimitTiers = map[RateLimitTier]rateLimit{
+	Tier1:    {perMinute: 1, burst: 3},
+	Tier2:    {perMinute: 20, burst: 20},
+	Tier3:    {perMinute: 50, burst: 50},
+	Tier4:    {perMinute: 100, burst: 100},
+	TierPost: {perMinute: 60, burst: 3},
+}
+
+func (self rateLimit) interval() time.Duration {
+	return time.Minute / time.Duration(self.perMinute)
+}
+
+func (self rateLimit) tolerance() time.Duration {
+	return self.interval() * time.Duration(self.burst-1)
+}
+
+// rateLimiter queues calls per key so that each key stays within its
+// tier's budget. It's a GCRA: for each key we track the theoretical
+// arrival time of the next call and make callers wait when it's too far
+// in the future.
+type rateLimiter struct {
+	mutex sync.Mutex
+	tats  map[string]time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{tats: make(map[string]time.Time)}
+}
+
+// Reserve a slot for key and return how long the caller must wait
+// before using it.
+func (self *rateLimiter) reserve(key string, tier RateLimitTier) time.Duration {
+	limit := rateLimitTiers[tier]
+	now := time.Now()
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	tat := self.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	delay := tat.Sub(now) - limit.tolerance()
+	self.tats[key] = tat.Add(limit.interval())
+
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Block key until at least 'until', e.g. after Slack sent Retry-After.
+func (self *rateLimiter) holdUntil(key string, tier RateLimitTier, until time.Time) {
+	limit := rateLimitTiers[tier]
+	tat := until.Add(limit.tolerance())
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if tat.After(self.tats[key]) {
+		self.tats[key] = tat
+	}
+}
+
+// Wait for a slot for key, or until ctx is done.
+func (self *rateLimiter) wait(ctx context.Context, key string, tier RateLimitTier) error {
+	return sleepContext(ctx, self.reserve(key, tier))
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func parseRetryAfter(hdr http.Header) time.Duration {
+	secs, err := strconv.Atoi(hdr.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,99 @@
+package slopher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Delays are computed from time.Now(), so allow a little slack.
+func approx(got, want time.Duration) bool {
+	return got <= want && got > want-100*time.Millisecond
+}
+
+func TestRateLimiterBurstThenInterval(t *testing.T) {
+	limiter := newRateLimiter()
+
+	// TierPost: a burst of 3, then 1 per second.
+	for i := 0; i < 3; i++ {
+		if delay := limiter.reserve("C1", TierPost); delay != 0 {
+			t.Fatalf("call %d in burst waited %v", i+1, delay)
+		}
+	}
+	if delay := limiter.reserve("C1", TierPost); !approx(delay, time.Second) {
+		t.Errorf("4th call waits %v, want ~1s", delay)
+	}
+	if delay := limiter.reserve("C1", TierPost); !approx(delay, 2*time.Second) {
+		t.Errorf("5th call waits %v, want ~2s", delay)
+	}
+
+	// Keys don't share a budget.
+	if delay := limiter.reserve("C2", TierPost); delay != 0 {
+		t.Errorf("other key waits %v", delay)
+	}
+}
+
+func TestRateLimiterTiers(t *testing.T) {
+	tests := []struct {
+		tier     RateLimitTier
+		burst    int
+		interval time.Duration
+	}{
+		{Tier1, 3, time.Minute},
+		{Tier2, 20, 3 * time.Second},
+		{Tier3, 50, 1200 * time.Millisecond},
+		{Tier4, 100, 600 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		limiter := newRateLimiter()
+		for i := 0; i < test.burst; i++ {
+			if delay := limiter.reserve("k", test.tier); delay != 0 {
+				t.Fatalf("tier %d: call %d in burst waited %v", test.tier, i+1, delay)
+			}
+		}
+		if delay := limiter.reserve("k", test.tier); !approx(delay, test.interval) {
+			t.Errorf("tier %d: call after burst waits %v, want ~%v",
+				test.tier, delay, test.interval)
+		}
+	}
+}
+
+func TestRateLimiterHoldUntil(t *testing.T) {
+	limiter := newRateLimiter()
+	limiter.reserve("k", Tier3)
+
+	limiter.holdUntil("k", Tier3, time.Now().Add(10*time.Second))
+	if delay := limiter.reserve("k", Tier3); !approx(delay, 10*time.Second) {
+		t.Errorf("call after hold waits %v, want ~10s", delay)
+	}
+
+	// An earlier hold doesn't shorten the wait.
+	limiter.holdUntil("k", Tier3, time.Now().Add(time.Second))
+	if delay := limiter.reserve("k", Tier3); delay < 10*time.Second {
+		t.Errorf("earlier hold shortened the wait to %v", delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"", defaultRetryAfter},
+		{"-1", defaultRetryAfter},
+		{"soon", defaultRetryAfter},
+	}
+
+	for _, test := range tests {
+		hdr := http.Header{}
+		if test.value != "" {
+			hdr.Set("Retry-After", test.value)
+		}
+		if got := parseRetryAfter(hdr); got != test.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+// Args identifying item for the reactions.*, pins.* and stars.* methods.
+// pins.* also wants the channel for files.
+func (self *ItemRef) args() APIArgs {
+	args := APIArgs{}
+	switch {
+	case self.FileCommentID != "":
+		args["file_comment"] = self.FileCommentID
+	case self.FileID != "":
+		args["file"] = self.FileID
+	case self.TS != "":
+		args["timestamp"] = self.TS
+	}
+	if self.ChannelID != "" {
+		args["channel"] = self.ChannelID
+	}
+	return args
+}
+
+// ReactedItem is an item and its reactions, as returned by
+// reactions.get and reactions.list. Message or File is set according to
+// Type.
+type ReactedItem struct {
+	Type      string      `json:"type"`
+	ChannelID string      `json:"channel,omitempty"`
+	Message   *Message    `json:"message,omitempty"`
+	File      *SharedFile `json:"file,omitempty"`
+}
+
+func (self *ReactedItem) Reactions() []Reaction {
+	if self.Message != nil {
+		return self.Message.Reactions
+	}
+	if self.File != nil {
+		return self.File.Reactions
+	}
+	return nil
+}
+
+type ReactionsAddResponse struct {
+	baseAPIResponse
+}
+
+// ReactionsAdd adds the reaction name (an emoji name without colons) to
+// a message.
+func (self *Client) ReactionsAdd(ctx context.Context, name string, item *ItemRef) (*ReactionsAddResponse, error) {
+	resp := &ReactionsAddResponse{}
+	args := withArgs(item.args(), "name", name)
+
+	err := self.apiCall(ctx, "reactions.add", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ReactionsRemoveResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) ReactionsRemove(ctx context.Context, name string, item *ItemRef) (*ReactionsRemoveResponse, error) {
+	resp := &ReactionsRemoveResponse{}
+	args := withArgs(item.args(), "name", name)
+
+	err := self.apiCall(ctx, "reactions.remove", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ReactionsGetResponse struct {
+	baseAPIResponse
+	ReactedItem
+}
+
+// ReactionsGet returns an item with its reactions. Unless full is set,
+// Slack may truncate the lists of users.
+func (self *Client) ReactionsGet(ctx context.Context, item *ItemRef, full bool) (*ReactionsGetResponse, error) {
+	resp := &ReactionsGetResponse{}
+	args := item.args()
+
+	if full {
+		args["full"] = "true"
+	}
+
+	err := self.apiCall(ctx, "reactions.get", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type ReactionsListResponse struct {
+	baseAPIResponse
+
+	Items []*ReactedItem `json:"items"`
+	// Older responses page this way instead of with cursors
+	Paging *Paging `json:"paging,omitempty"`
+}
+
+func (self *ReactionsListResponse) pageInfo() *Paging {
+	return self.Paging
+}
+
+func (self *ReactionsListResponse) numItems() int {
+	return len(self.Items)
+}
+
+func (self *ReactionsListResponse) appendItems(page PagedResponse) {
+	self.Items = append(self.Items, page.(*ReactionsListResponse).Items...)
+}
+
+func (self *ReactionsListResponse) truncateItems(n int) {
+	self.Items = self.Items[:n]
+}
+
+// ReactionsList returns the items user_id has reacted to, following
+// pagination. An empty user_id means the token's user.
+func (self *Client) ReactionsList(ctx context.Context, user_id string, full bool) (*ReactionsListResponse, error) {
+	args := APIArgs{}
+	if user_id != "" {
+		args["user"] = user_id
+	}
+	if full {
+		args["full"] = "true"
+	}
+
+	resp, err := self.ReactionsListPages(args).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ReactionsListResponse), nil
+}
+
+func (self *Client) ReactionsListPages(args APIArgs) *Paginator {
+	return self.newPaginator("reactions.list", args, func() PagedResponse {
+		return &ReactionsListResponse{}
+	})
+}
+
+// React adds the reaction name to the message, using the Client in the
+// context.
+func (self *Message) React(ctx context.Context, name string) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = cli.ReactionsAdd(ctx, name, NewMessageItemRef(self.ChannelID, self.TS))
+	return err
+}
+
+func (self *Message) Unreact(ctx context.Context, name string) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = cli.ReactionsRemove(ctx, name, NewMessageItemRef(self.ChannelID, self.TS))
+	return err
+}
@@ -0,0 +1,151 @@
+package slopher
+
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type Reminder struct {
+	ID      string `json:"id"`
+	Creator string `json:"creator"`
+	// Who's reminded
+	UserID    string `json:"user"`
+	Text      string `json:"text"`
+	Recurring bool   `json:"recurring"`
+	// Next time the reminder fires. Zero for recurring reminders.
+	Time EpochTime `json:"time"`
+	// When it was completed, or zero
+	CompleteTS EpochTime `json:"complete_ts"`
+}
+
+func (self *Reminder) IsComplete() bool {
+	return !self.CompleteTS.IsZero()
+}
+
+// ReminderTarget is who a reminder is for: an *Entity. reminders.add
+// can only remind users, so there are no channel reminders.
+type ReminderTarget interface {
+	reminderArgs() APIArgs
+}
+
+func (self *Entity) reminderArgs() APIArgs {
+	// Bots have User records too, which is what reminders want.
+	if self.User != nil {
+		return APIArgs{"user": self.User.ID}
+	}
+	return APIArgs{"user": self.GetID()}
+}
+
+// ReminderResponse is returned by the reminders.* methods that return a
+// single reminder.
+type ReminderResponse struct {
+	baseAPIResponse
+
+	Reminder *Reminder `json:"reminder"`
+}
+
+// RemindersAdd has Slack remind target of text at the given time. A nil
+// target means the token's user. Reminders need a user token.
+func (self *Client) RemindersAdd(ctx context.Context, target ReminderTarget, text string, at time.Time) (*ReminderResponse, error) {
+	args := APIArgs{}
+	if target != nil {
+		args = target.reminderArgs()
+	}
+	args["text"] = text
+	args["time"] = strconv.FormatInt(at.Unix(), 10)
+
+	return self.reminderCall(ctx, "reminders.add", args)
+}
+
+// RemindersAddIn is RemindersAdd for a reminder d from now.
+func (self *Client) RemindersAddIn(ctx context.Context, target ReminderTarget, text string, d time.Duration) (*ReminderResponse, error) {
+	return self.RemindersAdd(ctx, target, text, time.Now().Add(d))
+}
+
+func (self *Client) RemindersInfo(ctx context.Context, reminder_id string) (*ReminderResponse, error) {
+	args := APIArgs{"reminder": reminder_id}
+	return self.reminderCall(ctx, "reminders.info", args)
+}
+
+type RemindersCompleteResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) RemindersComplete(ctx context.Context, reminder_id string) (*RemindersCompleteResponse, error) {
+	resp := &RemindersCompleteResponse{}
+	args := APIArgs{"reminder": reminder_id}
+
+	err := self.apiCall(ctx, "reminders.complete", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type RemindersDeleteResponse struct {
+	baseAPIResponse
+}
+
+func (self *Client) RemindersDelete(ctx context.Context, reminder_id string) (*RemindersDeleteResponse, error) {
+	resp := &RemindersDeleteResponse{}
+	args := APIArgs{"reminder": reminder_id}
+
+	err := self.apiCall(ctx, "reminders.delete", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type RemindersListResponse struct {
+	baseAPIResponse
+
+	Reminders []*Reminder `json:"reminders"`
+}
+
+// RemindersList returns the reminders created by or for the token's
+// user.
+func (self *Client) RemindersList(ctx context.Context) (*RemindersListResponse, error) {
+	resp := &RemindersListResponse{}
+
+	err := self.apiCall(ctx, "reminders.list", nil, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// For the methods that return ReminderResponse.
+func (self *Client) reminderCall(ctx context.Context, method string, args APIArgs) (*ReminderResponse, error) {
+	resp := &ReminderResponse{}
+
+	err := self.apiCall(ctx, method, args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Remind has Slack remind the entity of text at the given time, using
+// the Client in the context.
+func (self *Entity) Remind(ctx context.Context, text string, at time.Time) (*Reminder, error) {
+	return remind(ctx, self, text, at)
+}
+
+func remind(ctx context.Context, target ReminderTarget, text string, at time.Time) (*Reminder, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cli.RemindersAdd(ctx, target, text, at)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Reminder, nil
+}
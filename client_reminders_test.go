@@ -0,0 +1,37 @@
+package slopher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestRemindersAdd(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	client.UserToken = "xoxp-test"
+	ctx := context.Background()
+	at := time.Now().Add(time.Hour)
+
+	resp, err := client.RemindersAdd(ctx, nil, "stretch", at)
+	if err != nil {
+		t.Fatalf("RemindersAdd(nil): %v", err)
+	}
+	if resp.Reminder.UserID != srv.Self.ID {
+		t.Errorf("UserID = %q, want the token's user %q", resp.Reminder.UserID, srv.Self.ID)
+	}
+
+	user := &slopher.Entity{User: &slopher.User{ID: "U2"}}
+	resp, err = client.RemindersAdd(ctx, user, "lunch", at)
+	if err != nil {
+		t.Fatalf("RemindersAdd(user): %v", err)
+	}
+	if resp.Reminder.UserID != "U2" || resp.Reminder.Text != "lunch" {
+		t.Errorf("Reminder = %+v", resp.Reminder)
+	}
+}
@@ -0,0 +1,77 @@
+package slopher
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy controls how Client retries calls that failed for
+// transient reasons: network errors, HTTP 5xx responses and Slack errors
+// like "internal_error". Only methods in IdempotentMethods are retried,
+// so that e.g. chat.postMessage isn't posted twice.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	IdempotentMethods map[string]bool
+}
+
+// Slack error codes that are worth retrying.
+var transientErrors = map[string]bool{
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// DefaultRetryPolicy returns a policy allowing 3 attempts of the
+// read-only and otherwise idempotent methods the Client knows about.
+func DefaultRetryPolicy() *RetryPolicy {
+	idempotent := make(map[string]bool)
+	for name, meth := range apiMethods {
+		if meth.idempotent {
+			idempotent[name] = true
+		}
+	}
+	return &RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         500 * time.Millisecond,
+		MaxDelay:          30 * time.Second,
+		IdempotentMethods: idempotent,
+	}
+}
+
+func (self *RetryPolicy) shouldRetry(method string, attempt int, err error) bool {
+	if self == nil || attempt >= self.MaxAttempts {
+		return false
+	}
+	return self.IdempotentMethods[method] && isTransientError(err)
+}
+
+// Exponential backoff with jitter: somewhere between half and all of
+// BaseDelay * 2^(attempt-1), capped at MaxDelay.
+func (self *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := self.BaseDelay << uint(attempt-1)
+	if delay > self.MaxDelay || delay <= 0 {
+		delay = self.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func isTransientError(err error) bool {
+	if serr, ok := AsSlackError(err); ok {
+		return serr.StatusCode >= 500 || transientErrors[serr.Code]
+	}
+	if err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
@@ -0,0 +1,144 @@
+package slopher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&SlackError{Code: "internal_error"}, true},
+		{&SlackError{Code: "http_error", StatusCode: 503}, true},
+		{fmt.Errorf("wrapped: %w", &SlackError{Code: "fatal_error"}), true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&SlackError{Code: "channel_not_found"}, false},
+		{&SlackError{Code: "ratelimited", StatusCode: 429}, false},
+		{errors.New("other"), false},
+	}
+
+	for _, test := range tests {
+		if got := isTransientError(test.err); got != test.want {
+			t.Errorf("isTransientError(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	transient := &SlackError{Code: "internal_error"}
+
+	if !policy.shouldRetry("users.info", 1, transient) {
+		t.Error("idempotent method with a transient error isn't retried")
+	}
+	if policy.shouldRetry("chat.postMessage", 1, transient) {
+		t.Error("chat.postMessage is retried")
+	}
+	if policy.shouldRetry("users.info", 1, &SlackError{Code: "user_not_found"}) {
+		t.Error("permanent error is retried")
+	}
+	if policy.shouldRetry("users.info", policy.MaxAttempts, transient) {
+		t.Error("retried past MaxAttempts")
+	}
+
+	var nil_policy *RetryPolicy
+	if nil_policy.shouldRetry("users.info", 1, transient) {
+		t.Error("nil policy retries")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{100, time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(test.attempt)
+			if delay < test.max/2 || delay > test.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v",
+					test.attempt, delay, test.max/2, test.max)
+				break
+			}
+		}
+	}
+}
+
+// Answers each call with the next of responses.
+func scriptedTransport(calls *int, responses ...string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		body := responses[*calls]
+		*calls++
+		if strings.HasPrefix(body, "5") {
+			return textResponse(http.StatusServiceUnavailable, body), nil
+		}
+		return textResponse(http.StatusOK, body), nil
+	}
+}
+
+func TestRetryTreatsAlreadyDoneAsSuccess(t *testing.T) {
+	ctx := context.Background()
+	item := &ItemRef{ChannelID: "C1", TS: "1.000001"}
+
+	// The first attempt reacted, but its response was lost.
+	var calls int
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.RetryPolicy.BaseDelay = time.Millisecond
+	client.SetTransport(scriptedTransport(&calls, "503 Service Unavailable",
+		`{"ok": false, "error": "already_reacted"}`))
+
+	if _, err := client.ReactionsAdd(ctx, "thumbsup", item); err != nil {
+		t.Errorf("ReactionsAdd after a retry: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("sent %d requests, want 2", calls)
+	}
+
+	// Without a retry it's still an error.
+	calls = 0
+	client.SetTransport(scriptedTransport(&calls, `{"ok": false, "error": "already_reacted"}`))
+	if _, err := client.ReactionsAdd(ctx, "thumbsup", item); !IsSlackError(err, "already_reacted") {
+		t.Errorf("ReactionsAdd error = %v, want already_reacted", err)
+	}
+}
+
+func TestRetryTreatsAlreadyPinnedAsSuccess(t *testing.T) {
+	var calls int
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.RetryPolicy.BaseDelay = time.Millisecond
+	client.SetTransport(scriptedTransport(&calls, "503 Service Unavailable",
+		`{"ok": false, "error": "already_pinned"}`))
+
+	item := &ItemRef{ChannelID: "C1", TS: "1.000001"}
+	if _, err := client.PinsAdd(context.Background(), item); err != nil {
+		t.Errorf("PinsAdd after a retry: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("sent %d requests, want 2", calls)
+	}
+}
@@ -0,0 +1,259 @@
+package slopher
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	SearchSortScore     = "score"
+	SearchSortTimestamp = "timestamp"
+)
+
+// SearchOptions are the optional parameters of the search.* methods.
+type SearchOptions struct {
+	// SearchSortScore (the default) or SearchSortTimestamp
+	Sort string
+	// "asc" or "desc" (the default)
+	SortDir string
+	// Wrap matched terms in U+E000 and U+E001
+	Highlight bool
+	// Results per page, up to 100. Slack defaults to 20.
+	Count int
+	// Page to start at, from 1
+	Page int
+}
+
+func (self *SearchOptions) args(query string) APIArgs {
+	args := APIArgs{"query": query}
+	if self == nil {
+		return args
+	}
+	if self.Sort != "" {
+		args["sort"] = self.Sort
+	}
+	if self.SortDir != "" {
+		args["sort_dir"] = self.SortDir
+	}
+	if self.Highlight {
+		args["highlight"] = "true"
+	}
+	if self.Count > 0 {
+		args["count"] = strconv.Itoa(self.Count)
+	}
+	if self.Page > 0 {
+		args["page"] = strconv.Itoa(self.Page)
+	}
+	return args
+}
+
+// SearchChannel is the channel a search match was found in.
+type SearchChannel struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	IsChannel   bool   `json:"is_channel"`
+	IsGroup     bool   `json:"is_group"`
+	IsIM        bool   `json:"is_im"`
+	IsMpIM      bool   `json:"is_mpim"`
+	IsPrivate   bool   `json:"is_private"`
+	IsShared    bool   `json:"is_shared"`
+	IsExtShared bool   `json:"is_ext_shared"`
+}
+
+// SearchMessage is a message matched by search.messages. ChannelID is
+// set from Channel.
+type SearchMessage struct {
+	Message
+
+	Channel   *SearchChannel `json:"channel"`
+	Username  string         `json:"username"`
+	Permalink string         `json:"permalink"`
+}
+
+// Search results have a channel object where messages have a channel
+// ID.
+func (self *SearchMessage) UnmarshalJSON(data []byte) error {
+	var meta struct {
+		Channel   *SearchChannel `json:"channel"`
+		Username  string         `json:"username"`
+		Permalink string         `json:"permalink"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	delete(fields, "channel")
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &self.Message); err != nil {
+		return err
+	}
+
+	self.Channel = meta.Channel
+	self.Username = meta.Username
+	self.Permalink = meta.Permalink
+	if self.Channel != nil {
+		self.ChannelID = self.Channel.ID
+	}
+	return nil
+}
+
+type SearchMessages struct {
+	Total   int              `json:"total"`
+	Paging  *Paging          `json:"paging"`
+	Matches []*SearchMessage `json:"matches"`
+}
+
+type SearchFiles struct {
+	Total   int           `json:"total"`
+	Paging  *Paging       `json:"paging"`
+	Matches []*SharedFile `json:"matches"`
+}
+
+/*
+** search.messages
+ */
+type SearchMessagesResponse struct {
+	baseAPIResponse
+
+	Query    string          `json:"query"`
+	Messages *SearchMessages `json:"messages"`
+}
+
+func (self *SearchMessagesResponse) pageInfo() *Paging {
+	if self.Messages == nil {
+		return nil
+	}
+	return self.Messages.Paging
+}
+
+func (self *SearchMessagesResponse) numItems() int {
+	if self.Messages == nil {
+		return 0
+	}
+	return len(self.Messages.Matches)
+}
+
+func (self *SearchMessagesResponse) appendItems(page PagedResponse) {
+	other := page.(*SearchMessagesResponse).Messages
+	if other == nil {
+		return
+	}
+	if self.Messages == nil {
+		self.Messages = &SearchMessages{Total: other.Total}
+	}
+	self.Messages.Matches = append(self.Messages.Matches, other.Matches...)
+}
+
+func (self *SearchMessagesResponse) truncateItems(n int) {
+	self.Messages.Matches = self.Messages.Matches[:n]
+}
+
+// SearchMessages returns one page of messages matching query. It needs a
+// user token. Use SearchMessagesPages for more.
+func (self *Client) SearchMessages(ctx context.Context, query string, opts *SearchOptions) (*SearchMessagesResponse, error) {
+	resp := &SearchMessagesResponse{}
+
+	err := self.apiCall(ctx, "search.messages", opts.args(query), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) SearchMessagesPages(query string, opts *SearchOptions) *Paginator {
+	return self.newPaginator("search.messages", opts.args(query), func() PagedResponse {
+		return &SearchMessagesResponse{}
+	})
+}
+
+/*
+** search.files
+ */
+type SearchFilesResponse struct {
+	baseAPIResponse
+
+	Query string       `json:"query"`
+	Files *SearchFiles `json:"files"`
+}
+
+func (self *SearchFilesResponse) pageInfo() *Paging {
+	if self.Files == nil {
+		return nil
+	}
+	return self.Files.Paging
+}
+
+func (self *SearchFilesResponse) numItems() int {
+	if self.Files == nil {
+		return 0
+	}
+	return len(self.Files.Matches)
+}
+
+func (self *SearchFilesResponse) appendItems(page PagedResponse) {
+	other := page.(*SearchFilesResponse).Files
+	if other == nil {
+		return
+	}
+	if self.Files == nil {
+		self.Files = &SearchFiles{Total: other.Total}
+	}
+	self.Files.Matches = append(self.Files.Matches, other.Matches...)
+}
+
+func (self *SearchFilesResponse) truncateItems(n int) {
+	self.Files.Matches = self.Files.Matches[:n]
+}
+
+// SearchFiles returns one page of files matching query. It needs a user
+// token. Use SearchFilesPages for more.
+func (self *Client) SearchFiles(ctx context.Context, query string, opts *SearchOptions) (*SearchFilesResponse, error) {
+	resp := &SearchFilesResponse{}
+
+	err := self.apiCall(ctx, "search.files", opts.args(query), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) SearchFilesPages(query string, opts *SearchOptions) *Paginator {
+	return self.newPaginator("search.files", opts.args(query), func() PagedResponse {
+		return &SearchFilesResponse{}
+	})
+}
+
+/*
+** search.all
+ */
+type SearchAllResponse struct {
+	baseAPIResponse
+
+	Query    string          `json:"query"`
+	Messages *SearchMessages `json:"messages"`
+	Files    *SearchFiles    `json:"files"`
+}
+
+// SearchAll returns one page each of the messages and files matching
+// query. It needs a user token.
+func (self *Client) SearchAll(ctx context.Context, query string, opts *SearchOptions) (*SearchAllResponse, error) {
+	resp := &SearchAllResponse{}
+
+	err := self.apiCall(ctx, "search.all", opts.args(query), resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,55 @@
+package slopher
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSearchMessageUnmarshal(t *testing.T) {
+	data := `{
+		"type": "message",
+		"user": "U1",
+		"username": "alice",
+		"ts": "1700000000.000100",
+		"text": "deploy is done",
+		"permalink": "https://example.slack.com/archives/C1/p1700000000000100",
+		"channel": {"id": "C1", "name": "general", "is_channel": true}
+	}`
+
+	var msg SearchMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if msg.ChannelID != "C1" || msg.Channel == nil || msg.Channel.Name != "general" {
+		t.Errorf("ChannelID = %q, Channel = %+v", msg.ChannelID, msg.Channel)
+	}
+	if msg.UserID != "U1" || msg.Username != "alice" || msg.TS != "1700000000.000100" ||
+		msg.Text != "deploy is done" {
+		t.Errorf("Message = %+v, Username = %q", msg.BaseMessage, msg.Username)
+	}
+	if msg.Permalink == "" {
+		t.Error("Permalink not set")
+	}
+}
+
+func TestSearchMessagesResponseUnmarshal(t *testing.T) {
+	data := `{"ok": true, "query": "deploy", "messages": {"total": 2,
+		"paging": {"count": 20, "total": 2, "page": 1, "pages": 1},
+		"matches": [
+			{"ts": "1.1", "text": "a", "channel": {"id": "C1"}},
+			{"ts": "2.2", "text": "b", "channel": {"id": "D1", "is_im": true}}
+		]}}`
+
+	var resp SearchMessagesResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if resp.numItems() != 2 || resp.pageInfo().Pages != 1 {
+		t.Fatalf("numItems = %d, pageInfo = %+v", resp.numItems(), resp.pageInfo())
+	}
+	if m := resp.Messages.Matches[1]; m.ChannelID != "D1" || !m.Channel.IsIM || m.Text != "b" {
+		t.Errorf("Matches[1] = %+v", m)
+	}
+}
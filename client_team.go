@@ -0,0 +1,185 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+/*
+** team.info
+ */
+type TeamInfoResponse struct {
+	baseAPIResponse
+
+	Team *Team `json:"team"`
+}
+
+// TeamInfo returns information about a team. An empty team_id means the
+// token's team.
+func (self *Client) TeamInfo(ctx context.Context, team_id string) (*TeamInfoResponse, error) {
+	resp := &TeamInfoResponse{}
+	args := APIArgs{}
+
+	if team_id != "" {
+		args["team"] = team_id
+	}
+
+	err := self.apiCall(ctx, "team.info", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** team.accessLogs
+ */
+
+// AccessLog is one user's logins from one IP address and user agent.
+type AccessLog struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	DateFirst EpochTime `json:"date_first"`
+	DateLast  EpochTime `json:"date_last"`
+	Count     int       `json:"count"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	ISP       string    `json:"isp"`
+	Country   string    `json:"country"`
+	Region    string    `json:"region"`
+}
+
+type TeamAccessLogsResponse struct {
+	baseAPIResponse
+
+	Logins []*AccessLog `json:"logins"`
+	Paging *Paging      `json:"paging,omitempty"`
+}
+
+func (self *TeamAccessLogsResponse) pageInfo() *Paging {
+	return self.Paging
+}
+
+func (self *TeamAccessLogsResponse) numItems() int {
+	return len(self.Logins)
+}
+
+func (self *TeamAccessLogsResponse) appendItems(page PagedResponse) {
+	self.Logins = append(self.Logins, page.(*TeamAccessLogsResponse).Logins...)
+}
+
+func (self *TeamAccessLogsResponse) truncateItems(n int) {
+	self.Logins = self.Logins[:n]
+}
+
+// TeamAccessLogs returns one page of the team's access logs. args may
+// include "before" (a Unix time), "count" and "page". It needs an admin's
+// user token. Use TeamAccessLogsPages for more.
+func (self *Client) TeamAccessLogs(ctx context.Context, args APIArgs) (*TeamAccessLogsResponse, error) {
+	pager := self.TeamAccessLogsPages(args)
+	if !pager.Next(ctx) {
+		return nil, pager.Err()
+	}
+
+	return pager.Page().(*TeamAccessLogsResponse), nil
+}
+
+func (self *Client) TeamAccessLogsPages(args APIArgs) *Paginator {
+	return self.newPaginator("team.accessLogs", args, func() PagedResponse {
+		return &TeamAccessLogsResponse{}
+	})
+}
+
+/*
+** team.billableInfo
+ */
+type BillableInfo struct {
+	BillingActive bool `json:"billing_active"`
+}
+
+type TeamBillableInfoResponse struct {
+	baseAPIResponse
+
+	// By user ID
+	BillableInfo map[string]*BillableInfo `json:"billable_info"`
+}
+
+// TeamBillableInfo returns whether users are billed for. An empty
+// user_id means all users. It needs an admin's user token.
+func (self *Client) TeamBillableInfo(ctx context.Context, user_id string) (*TeamBillableInfoResponse, error) {
+	resp := &TeamBillableInfoResponse{}
+	args := APIArgs{}
+
+	if user_id != "" {
+		args["user"] = user_id
+	}
+
+	err := self.apiCall(ctx, "team.billableInfo", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** team.integrationLogs
+ */
+
+// IntegrationLog records an app or integration being added, changed or
+// removed. ServiceID and ServiceType are set for integrations, AppID
+// and AppType for apps.
+type IntegrationLog struct {
+	ServiceID   string    `json:"service_id,omitempty"`
+	ServiceType string    `json:"service_type,omitempty"`
+	AppID       string    `json:"app_id,omitempty"`
+	AppType     string    `json:"app_type,omitempty"`
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	ChannelID   string    `json:"channel,omitempty"`
+	Date        EpochTime `json:"date"`
+	// "added", "removed", "enabled", "disabled" or "updated"
+	ChangeType string `json:"change_type"`
+	Reason     string `json:"reason,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+}
+
+type TeamIntegrationLogsResponse struct {
+	baseAPIResponse
+
+	Logs   []*IntegrationLog `json:"logs"`
+	Paging *Paging           `json:"paging,omitempty"`
+}
+
+func (self *TeamIntegrationLogsResponse) pageInfo() *Paging {
+	return self.Paging
+}
+
+func (self *TeamIntegrationLogsResponse) numItems() int {
+	return len(self.Logs)
+}
+
+func (self *TeamIntegrationLogsResponse) appendItems(page PagedResponse) {
+	self.Logs = append(self.Logs, page.(*TeamIntegrationLogsResponse).Logs...)
+}
+
+func (self *TeamIntegrationLogsResponse) truncateItems(n int) {
+	self.Logs = self.Logs[:n]
+}
+
+// TeamIntegrationLogs returns one page of the team's integration logs.
+// args may include "app_id", "service_id", "user", "change_type",
+// "count" and "page". It needs an admin's user token. Use
+// TeamIntegrationLogsPages for more.
+func (self *Client) TeamIntegrationLogs(ctx context.Context, args APIArgs) (*TeamIntegrationLogsResponse, error) {
+	pager := self.TeamIntegrationLogsPages(args)
+	if !pager.Next(ctx) {
+		return nil, pager.Err()
+	}
+
+	return pager.Page().(*TeamIntegrationLogsResponse), nil
+}
+
+func (self *Client) TeamIntegrationLogsPages(args APIArgs) *Paginator {
+	return self.newPaginator("team.integrationLogs", args, func() PagedResponse {
+		return &TeamIntegrationLogsResponse{}
+	})
+}
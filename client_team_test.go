@@ -0,0 +1,117 @@
+package slopher_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+// A team.info response as an admin token sees it, including a pref
+// TeamPrefs doesn't know about
+const teamInfoJSON = `{
+	"ok": true,
+	"team": {
+		"id": "T00000001",
+		"name": "slacktest",
+		"domain": "slacktest",
+		"msg_edit_window_mins": -1,
+		"prefs": {
+			"allow_message_deletion": true,
+			"compliance_export_start": 0,
+			"default_channels": ["C1", "C2"],
+			"display_real_names": false,
+			"hide_referers": true,
+			"msg_edit_window_mins": -1,
+			"require_at_for_mention": 0,
+			"warn_before_at_channel": "always",
+			"retention_type": 2,
+			"retention_duration": 90,
+			"group_retention_type": 1,
+			"group_retention_duration": 0,
+			"dm_retention_type": 0,
+			"dm_retention_duration": 0,
+			"who_can_archive_channels": "regular",
+			"who_can_at_channel": "ra",
+			"who_can_at_everyone": "admin",
+			"who_can_create_channels": "regular",
+			"who_can_create_groups": "ra",
+			"who_can_kick_channels": "admin",
+			"who_can_kick_groups": "regular",
+			"who_can_post_general": "owner",
+			"some_newer_pref": {"nested": true}
+		}
+	}
+}`
+
+func TestTeamInfoPrefs(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.Handle("team.info", func(req *slacktest.Request) interface{} {
+		return json.RawMessage(teamInfoJSON)
+	})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	resp, err := client.TeamInfo(context.Background(), "")
+	if err != nil {
+		t.Fatalf("TeamInfo: %v", err)
+	}
+
+	prefs := resp.Team.Prefs
+	want := &slopher.TeamPrefs{
+		AllowMessageDeletion:  true,
+		DefaultChannels:       []string{"C1", "C2"},
+		HideReferers:          true,
+		MsgEditWindowMins:     -1,
+		WarnBeforeAtChannel:   "always",
+		RetentionType:         slopher.RetentionDays,
+		RetentionDuration:     90,
+		GroupRetentionType:    slopher.RetentionKeepAllNoEdits,
+		WhoCanArchiveChannels: "regular",
+		WhoCanAtChannel:       "ra",
+		WhoCanAtEveryone:      "admin",
+		WhoCanCreateChannels:  "regular",
+		WhoCanCreateGroups:    "ra",
+		WhoCanKickChannels:    "admin",
+		WhoCanKickGroups:      "regular",
+		WhoCanPostGeneral:     "owner",
+	}
+	if !reflect.DeepEqual(prefs, want) {
+		t.Errorf("Prefs = %+v\nwant %+v", prefs, want)
+	}
+	if !prefs.ComplianceExportStart.IsZero() {
+		t.Errorf("ComplianceExportStart = %v, want zero", prefs.ComplianceExportStart)
+	}
+	if window, ok := prefs.MsgEditWindow(); ok || window != 0 {
+		t.Errorf("MsgEditWindow() = %v, %v, want no limit", window, ok)
+	}
+}
+
+func TestTeamMsgEditWindow(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.Team.Prefs = &slopher.TeamPrefs{MsgEditWindowMins: 30}
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	resp, err := client.TeamInfo(context.Background(), "")
+	if err != nil {
+		t.Fatalf("TeamInfo: %v", err)
+	}
+	if window, ok := resp.Team.Prefs.MsgEditWindow(); !ok || window != 30*time.Minute {
+		t.Errorf("MsgEditWindow() = %v, %v, want 30m", window, ok)
+	}
+
+	// Zero means messages can't be edited at all.
+	prefs := &slopher.TeamPrefs{}
+	if window, ok := prefs.MsgEditWindow(); !ok || window != 0 {
+		t.Errorf("MsgEditWindow() = %v, %v, want 0", window, ok)
+	}
+
+	if _, err := client.TeamInfo(context.Background(), "T999"); !slopher.IsSlackError(err, "team_not_found") {
+		t.Errorf("TeamInfo(T999) = %v", err)
+	}
+}
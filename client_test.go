@@ -0,0 +1,36 @@
+package slopher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestDoRequestCancelsCustomTransport(t *testing.T) {
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Never answers, so only the request's context can end this.
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	errch := make(chan error, 1)
+	go func() {
+		_, err := client.ChatDelete(ctx, "C1", "1.2")
+		errch <- err
+	}()
+
+	select {
+	case err := <-errch:
+		if err != context.DeadlineExceeded {
+			t.Errorf("ChatDelete = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ChatDelete didn't return after its context expired")
+	}
+}
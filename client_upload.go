@@ -0,0 +1,392 @@
+package slopher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+type UploadFileOptions struct {
+	Filename       string
+	FileType       string
+	Title          string
+	InitialComment string
+	// Upload into this thread. Requires exactly one channel.
+	ThreadTS string
+	Channels []string
+
+	// Size of the content, if known. Only used for progress reporting.
+	Size int64
+	// Called as the content is sent. total is Size (so 0 if unknown).
+	Progress func(sent, total int64)
+}
+
+// UploadFileReader uploads the contents of r via files.upload. The
+// content is streamed, not buffered in memory, so the call can only be
+// retried if r is also an io.Seeker.
+//
+// Slack is retiring files.upload; new code should use
+// UploadFilesExternal.
+func (self *Client) UploadFileReader(ctx context.Context, r io.Reader, opts *UploadFileOptions) (*UploadFileResponse, error) {
+	resp := &UploadFileResponse{}
+
+	if opts == nil {
+		opts = &UploadFileOptions{}
+	}
+
+	args := APIArgs{}
+
+	if opts.Filename != "" {
+		args["filename"] = opts.Filename
+	}
+	if opts.FileType != "" {
+		args["filetype"] = opts.FileType
+	}
+	if opts.Title != "" {
+		args["title"] = opts.Title
+	}
+	if opts.InitialComment != "" {
+		args["initial_comment"] = opts.InitialComment
+	}
+	if opts.ThreadTS != "" {
+		args["thread_ts"] = opts.ThreadTS
+	}
+	if opts.Channels != nil {
+		args["channels"] = strings.Join(opts.Channels, ",")
+	}
+
+	file, err := newFileUpload(opts.Filename, r, opts.Size, opts.Progress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = self.apiUpload(ctx, "files.upload", args, file, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type GetUploadURLExternalResponse struct {
+	baseAPIResponse
+
+	UploadURL string `json:"upload_url"`
+	FileID    string `json:"file_id"`
+}
+
+// GetUploadURLExternal is step 1 of the external upload flow: it
+// reserves a file ID and returns the URL to send length bytes to.
+// args may carry optional fields such as "alt_txt" or "snippet_type".
+func (self *Client) GetUploadURLExternal(ctx context.Context, filename string, length int64, args APIArgs) (*GetUploadURLExternalResponse, error) {
+	resp := &GetUploadURLExternalResponse{}
+
+	if args == nil {
+		args = APIArgs{}
+	}
+	args["filename"] = filename
+	args["length"] = strconv.FormatInt(length, 10)
+
+	err := self.apiCall(ctx, "files.getUploadURLExternal", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ExternalFile identifies an uploaded file to complete.
+type ExternalFile struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+type CompleteUploadOptions struct {
+	// Share to this channel. If empty, the files are uploaded privately.
+	ChannelID      string
+	ThreadTS       string
+	InitialComment string
+}
+
+type CompleteUploadExternalResponse struct {
+	baseAPIResponse
+
+	Files []*SharedFile `json:"files"`
+}
+
+// CompleteUploadExternal is the last step of the external upload flow,
+// finishing (and optionally sharing) any number of uploaded files.
+func (self *Client) CompleteUploadExternal(ctx context.Context, files []ExternalFile, opts *CompleteUploadOptions) (*CompleteUploadExternalResponse, error) {
+	resp := &CompleteUploadExternalResponse{}
+
+	if opts == nil {
+		opts = &CompleteUploadOptions{}
+	}
+
+	req := &struct {
+		Files          []ExternalFile `json:"files"`
+		ChannelID      string         `json:"channel_id,omitempty"`
+		ThreadTS       string         `json:"thread_ts,omitempty"`
+		InitialComment string         `json:"initial_comment,omitempty"`
+	}{
+		Files:          files,
+		ChannelID:      opts.ChannelID,
+		ThreadTS:       opts.ThreadTS,
+		InitialComment: opts.InitialComment,
+	}
+
+	err := self.apiCallRequest(ctx, "files.completeUploadExternal", req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ExternalUpload is one file to send with UploadFilesExternal.
+type ExternalUpload struct {
+	Filename    string
+	Title       string
+	AltText     string
+	SnippetType string
+	Reader      io.Reader
+	// Required unless Reader is an io.Seeker
+	Size     int64
+	Progress func(sent, total int64)
+}
+
+// UploadFilesExternal runs the whole external upload flow: it gets an
+// upload URL for each file, streams the file to it, and then completes
+// all of them with a single files.completeUploadExternal call.
+func (self *Client) UploadFilesExternal(ctx context.Context, uploads []*ExternalUpload, opts *CompleteUploadOptions) (*CompleteUploadExternalResponse, error) {
+	files := make([]ExternalFile, 0, len(uploads))
+
+	for _, upload := range uploads {
+		size := upload.Size
+		if size <= 0 {
+			seeker, ok := upload.Reader.(io.Seeker)
+			if !ok {
+				return nil, fmt.Errorf("Size is required to upload %s", upload.Filename)
+			}
+			var err error
+			if size, err = seekerSize(seeker); err != nil {
+				return nil, err
+			}
+		}
+
+		args := APIArgs{}
+		if upload.AltText != "" {
+			args["alt_txt"] = upload.AltText
+		}
+		if upload.SnippetType != "" {
+			args["snippet_type"] = upload.SnippetType
+		}
+
+		url_resp, err := self.GetUploadURLExternal(ctx, upload.Filename, size, args)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := newFileUpload(upload.Filename, upload.Reader, size, upload.Progress)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := self.uploadToURL(ctx, url_resp.UploadURL, file); err != nil {
+			return nil, err
+		}
+
+		files = append(files, ExternalFile{
+			ID:    url_resp.FileID,
+			Title: upload.Title,
+		})
+	}
+
+	return self.CompleteUploadExternal(ctx, files, opts)
+}
+
+// Step 2 of the external upload flow: send the file's bytes to the URL
+// from files.getUploadURLExternal.
+func (self *Client) uploadToURL(ctx context.Context, upload_url string, file *fileUpload) error {
+	for attempt := 1; ; attempt++ {
+		err := self.uploadToURLAttempt(ctx, upload_url, file)
+		if err == nil || ctx.Err() != nil || self.RetryPolicy == nil ||
+			attempt >= self.RetryPolicy.MaxAttempts || !isTransientError(err) {
+			return err
+		}
+
+		delay := self.RetryPolicy.backoff(attempt)
+		self.log.Log(LogWarn, "File upload failed, will retry",
+			"filename", file.filename, "attempt", attempt, "delay", delay,
+			"error", err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (self *Client) uploadToURLAttempt(ctx context.Context, upload_url string, file *fileUpload) error {
+	reader, err := file.open()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", upload_url, reader)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = file.size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	self.log.Log(LogDebug, "Uploading file", "filename", file.filename,
+		"size", file.size)
+
+	_, status, _, err := self.doRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return &SlackError{
+			Method:     "files.getUploadURLExternal",
+			Code:       "upload_failed",
+			StatusCode: status,
+		}
+	}
+
+	return nil
+}
+
+// Returns the number of bytes left in seeker, leaving it where it was.
+func seekerSize(seeker io.Seeker) (int64, error) {
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := seeker.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - cur, nil
+}
+
+var errUploadNotSeekable = errors.New("Can't resend upload: reader isn't seekable")
+var errUploadRestarted = errors.New("Upload was restarted")
+
+// fileUpload is a file's content to be sent in a request body, possibly
+// more than once.
+type fileUpload struct {
+	filename string
+	reader   io.Reader
+	size     int64
+	progress func(sent, total int64)
+
+	// Where reader started, if it's an io.Seeker
+	start  int64
+	opened bool
+
+	// The reader handed out by the last open(), and a func to wait for
+	// whatever is still copying from it (set by multipartBody).
+	cur  *guardedReader
+	wait func()
+}
+
+func newFileUpload(filename string, r io.Reader, size int64, progress func(sent, total int64)) (*fileUpload, error) {
+	file := &fileUpload{
+		filename: filename,
+		reader:   r,
+		size:     size,
+		progress: progress,
+		start:    -1,
+	}
+
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		file.start = start
+	}
+
+	return file, nil
+}
+
+// Returns a reader for the content, rewinding it if it's been read
+// before. The previous attempt may still be reading (the transport can
+// keep sending a body after the response arrives), so it's cut off and
+// waited for before rewinding.
+func (self *fileUpload) open() (io.Reader, error) {
+	if self.opened {
+		if self.start < 0 {
+			return nil, errUploadNotSeekable
+		}
+		self.cur.close()
+		if self.wait != nil {
+			self.wait()
+			self.wait = nil
+		}
+		if _, err := self.reader.(io.Seeker).Seek(self.start, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	self.opened = true
+
+	var reader io.Reader = self.reader
+	if self.progress != nil {
+		reader = &progressReader{
+			reader:   self.reader,
+			total:    self.size,
+			progress: self.progress,
+		}
+	}
+	self.cur = &guardedReader{reader: reader}
+	return self.cur, nil
+}
+
+// guardedReader stops reading once closed. close() returns only after
+// any Read in progress has finished.
+type guardedReader struct {
+	mu     sync.Mutex
+	reader io.Reader
+	closed bool
+}
+
+func (self *guardedReader) Read(p []byte) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.closed {
+		return 0, errUploadRestarted
+	}
+	return self.reader.Read(p)
+}
+
+func (self *guardedReader) close() {
+	self.mu.Lock()
+	self.closed = true
+	self.mu.Unlock()
+}
+
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (self *progressReader) Read(p []byte) (int, error) {
+	n, err := self.reader.Read(p)
+	if n > 0 {
+		self.sent += int64(n)
+		self.progress(self.sent, self.total)
+	}
+	return n, err
+}
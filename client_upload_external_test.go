@@ -0,0 +1,142 @@
+package slopher_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+// Fails the fail_at'th upload with a 503, after reading part of the
+// body.
+type flakyUploads struct {
+	mutex   sync.Mutex
+	fail_at int
+	uploads []string
+}
+
+func (self *flakyUploads) RoundTrip(req *http.Request) (*http.Response, error) {
+	var fail bool
+	if id := strings.TrimPrefix(req.URL.Path, "/upload/"); id != req.URL.Path {
+		self.mutex.Lock()
+		self.uploads = append(self.uploads, id)
+		fail = len(self.uploads) == self.fail_at
+		self.mutex.Unlock()
+	}
+
+	if !fail {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+
+	io.CopyN(ioutil.Discard, req.Body, 3)
+	req.Body.Close()
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("try again")),
+	}, nil
+}
+
+func TestUploadFilesExternal(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	client.RetryPolicy.BaseDelay = time.Millisecond
+	flaky := &flakyUploads{fail_at: 2}
+	client.SetTransport(flaky)
+
+	// No Size: it's found by seeking, from the current offset.
+	seeker := bytes.NewReader([]byte("skip:seekable content"))
+	seeker.Seek(5, io.SeekStart)
+
+	var progress []int64
+	uploads := []*slopher.ExternalUpload{
+		{
+			Filename: "one.txt",
+			Title:    "One",
+			Reader:   strings.NewReader("first file"),
+			Size:     int64(len("first file")),
+		},
+		{
+			Filename: "two.txt",
+			Title:    "Two",
+			Reader:   seeker,
+			Progress: func(sent, total int64) {
+				progress = append(progress, total)
+			},
+		},
+	}
+
+	resp, err := client.UploadFilesExternal(context.Background(), uploads,
+		&slopher.CompleteUploadOptions{ChannelID: "C1"})
+	if err != nil {
+		t.Fatalf("UploadFilesExternal: %v", err)
+	}
+
+	if len(resp.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(resp.Files))
+	}
+	ids := []string{resp.Files[0].ID, resp.Files[1].ID}
+
+	// The second file was sent again from where it started.
+	if got := strings.Join(flaky.uploads, " "); got != ids[0]+" "+ids[1]+" "+ids[1] {
+		t.Errorf("uploads = %s", got)
+	}
+
+	contents := []string{"first file", "seekable content"}
+	for i, id := range ids {
+		got, ok := srv.FileContent(id)
+		if !ok || string(got) != contents[i] {
+			t.Errorf("%s content = %q, want %q", id, got, contents[i])
+		}
+	}
+	for _, total := range progress {
+		if total != int64(len("seekable content")) {
+			t.Errorf("progress total = %d", total)
+		}
+	}
+
+	for i, file := range resp.Files {
+		title := []string{"One", "Two"}[i]
+		if file.Title != title || file.Size != int64(len(contents[i])) {
+			t.Errorf("file %d = %+v", i, file)
+		}
+		if len(file.ChannelIDs) != 1 || file.ChannelIDs[0] != "C1" {
+			t.Errorf("file %d ChannelIDs = %v", i, file.ChannelIDs)
+		}
+	}
+
+	// One URL per file, then a single completion for both.
+	var methods []string
+	var sizes []string
+	for _, req := range srv.Requests() {
+		methods = append(methods, req.Method)
+		if req.Method == "files.getUploadURLExternal" {
+			sizes = append(sizes, req.Args["length"])
+		}
+		if req.Method == "files.completeUploadExternal" {
+			var files []slopher.ExternalFile
+			json.Unmarshal([]byte(req.Args["files"]), &files)
+			if len(files) != 2 {
+				t.Errorf("completed %v", files)
+			}
+		}
+	}
+	want_methods := "files.getUploadURLExternal files.getUploadURLExternal files.completeUploadExternal"
+	if got := strings.Join(methods, " "); got != want_methods {
+		t.Errorf("methods = %s, want %s", got, want_methods)
+	}
+	if got := strings.Join(sizes, " "); got != "10 16" {
+		t.Errorf("lengths = %s, want 10 16", got)
+	}
+}
@@ -0,0 +1,78 @@
+package slopher
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (self roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return self(req)
+}
+
+func textResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Retry-After": {"0"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestUploadFileReaderRetriesAfterRateLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var calls int32
+	client := NewClient("https://slack.test/api", "xoxb-test", nil)
+	client.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Answer right away and keep reading the body afterwards,
+			// as a transport may, so the retry overlaps this attempt.
+			go func() {
+				io.Copy(ioutil.Discard, req.Body)
+				req.Body.Close()
+			}()
+			return textResponse(http.StatusTooManyRequests, `{"ok": false, "error": "ratelimited"}`), nil
+		}
+
+		defer req.Body.Close()
+		_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		form, err := multipart.NewReader(req.Body, params["boundary"]).ReadForm(1 << 20)
+		if err != nil {
+			return nil, err
+		}
+		f, err := form.File["file"][0].Open()
+		if err != nil {
+			return nil, err
+		}
+		got, _ := ioutil.ReadAll(f)
+		if !bytes.Equal(got, content) {
+			t.Errorf("uploaded %d bytes, want %d", len(got), len(content))
+		}
+		return textResponse(http.StatusOK, `{"ok": true, "file": {"id": "F1"}}`), nil
+	}))
+
+	resp, err := client.UploadFileReader(context.Background(), bytes.NewReader(content),
+		&UploadFileOptions{Filename: "data.bin"})
+	if err != nil {
+		t.Fatalf("UploadFileReader: %v", err)
+	}
+	if resp.File == nil || resp.File.ID != "F1" {
+		t.Errorf("File = %+v", resp.File)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("transport saw %d calls, want 2", n)
+	}
+}
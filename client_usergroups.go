@@ -0,0 +1,191 @@
+package slopher
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+type UserGroupPrefs struct {
+	Channels []string `json:"channels"`
+	Groups   []string `json:"groups"`
+}
+
+// UserGroup is a user group, which RTM events call a subteam.
+type UserGroup struct {
+	ID          string          `json:"id"`
+	TeamID      string          `json:"team_id"`
+	IsUserGroup bool            `json:"is_usergroup"`
+	IsExternal  bool            `json:"is_external"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Handle      string          `json:"handle"`
+	AutoType    string          `json:"auto_type"`
+	CreatedBy   string          `json:"created_by"`
+	UpdatedBy   string          `json:"updated_by"`
+	DeletedBy   string          `json:"deleted_by"`
+	DateCreate  EpochTime       `json:"date_create"`
+	DateUpdate  EpochTime       `json:"date_update"`
+	DateDelete  EpochTime       `json:"date_delete"`
+	Prefs       *UserGroupPrefs `json:"prefs,omitempty"`
+	// Only returned when users are asked for
+	Users []string `json:"users,omitempty"`
+	// Sometimes sent as a string
+	UserCount json.Number `json:"user_count,omitempty"`
+}
+
+// IsDisabled returns true if the group has been disabled.
+func (self *UserGroup) IsDisabled() bool {
+	// Zero when enabled
+	return !self.DateDelete.IsZero()
+}
+
+// UserGroupOptions are the optional settings for UsergroupsCreate and
+// UsergroupsUpdate. Empty values are left alone.
+type UserGroupOptions struct {
+	// For UsergroupsUpdate only
+	Name        string
+	Handle      string
+	Description string
+	// Default channels for members
+	Channels []string
+}
+
+func (self *UserGroupOptions) args() APIArgs {
+	args := APIArgs{}
+	if self == nil {
+		return args
+	}
+	if self.Name != "" {
+		args["name"] = self.Name
+	}
+	if self.Handle != "" {
+		args["handle"] = self.Handle
+	}
+	if self.Description != "" {
+		args["description"] = self.Description
+	}
+	if len(self.Channels) > 0 {
+		args["channels"] = strings.Join(self.Channels, ",")
+	}
+	return args
+}
+
+// UserGroupResponse is returned by the usergroups.* methods that return
+// a single group.
+type UserGroupResponse struct {
+	baseAPIResponse
+
+	UserGroup *UserGroup `json:"usergroup"`
+}
+
+type UsergroupsListResponse struct {
+	baseAPIResponse
+
+	UserGroups []*UserGroup `json:"usergroups"`
+}
+
+func (self *Client) UsergroupsList(ctx context.Context, include_users, include_disabled bool) (*UsergroupsListResponse, error) {
+	resp := &UsergroupsListResponse{}
+	args := APIArgs{"include_count": "true"}
+
+	if include_users {
+		args["include_users"] = "true"
+	}
+	if include_disabled {
+		args["include_disabled"] = "true"
+	}
+
+	err := self.apiCall(ctx, "usergroups.list", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) UsergroupsCreate(ctx context.Context, name string, opts *UserGroupOptions) (*UserGroupResponse, error) {
+	args := withArgs(opts.args(), "name", name)
+	return self.userGroupCall(ctx, "usergroups.create", args)
+}
+
+func (self *Client) UsergroupsUpdate(ctx context.Context, usergroup_id string, opts *UserGroupOptions) (*UserGroupResponse, error) {
+	args := withArgs(opts.args(), "usergroup", usergroup_id)
+	return self.userGroupCall(ctx, "usergroups.update", args)
+}
+
+func (self *Client) UsergroupsEnable(ctx context.Context, usergroup_id string) (*UserGroupResponse, error) {
+	args := APIArgs{"usergroup": usergroup_id}
+	return self.userGroupCall(ctx, "usergroups.enable", args)
+}
+
+func (self *Client) UsergroupsDisable(ctx context.Context, usergroup_id string) (*UserGroupResponse, error) {
+	args := APIArgs{"usergroup": usergroup_id}
+	return self.userGroupCall(ctx, "usergroups.disable", args)
+}
+
+type UsergroupsUsersListResponse struct {
+	baseAPIResponse
+
+	Users []string `json:"users"`
+}
+
+func (self *Client) UsergroupsUsersList(ctx context.Context, usergroup_id string, include_disabled bool) (*UsergroupsUsersListResponse, error) {
+	resp := &UsergroupsUsersListResponse{}
+	args := APIArgs{"usergroup": usergroup_id}
+
+	if include_disabled {
+		args["include_disabled"] = "true"
+	}
+
+	err := self.apiCall(ctx, "usergroups.users.list", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// UsergroupsUsersUpdate replaces the members of a group.
+func (self *Client) UsergroupsUsersUpdate(ctx context.Context, usergroup_id string, user_ids []string) (*UserGroupResponse, error) {
+	args := APIArgs{
+		"usergroup": usergroup_id,
+		"users":     strings.Join(user_ids, ","),
+	}
+	return self.userGroupCall(ctx, "usergroups.users.update", args)
+}
+
+// For the methods that return UserGroupResponse.
+func (self *Client) userGroupCall(ctx context.Context, method string, args APIArgs) (*UserGroupResponse, error) {
+	resp := &UserGroupResponse{}
+
+	err := self.apiCall(ctx, method, args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** Mentions
+ */
+
+// Matches <!subteam^S123> and <!subteam^S123|@handle>
+var subteamMentionRegexp = regexp.MustCompile(`<!subteam\^([A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// ParseSubteamMentions returns the IDs of the user groups mentioned in
+// message text, in order and without duplicates.
+func ParseSubteamMentions(text string) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range subteamMentionRegexp.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			ids = append(ids, match[1])
+		}
+	}
+	return ids
+}
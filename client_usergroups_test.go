@@ -0,0 +1,179 @@
+package slopher_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestUsergroupsAPI(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	ctx := context.Background()
+
+	created, err := client.UsergroupsCreate(ctx, "Ops Team", &slopher.UserGroupOptions{
+		Handle:   "ops",
+		Channels: []string{"C1", "C2"},
+	})
+	if err != nil {
+		t.Fatalf("UsergroupsCreate: %v", err)
+	}
+	ug := created.UserGroup
+	if ug == nil || ug.Handle != "ops" || ug.Prefs == nil ||
+		!reflect.DeepEqual(ug.Prefs.Channels, []string{"C1", "C2"}) {
+		t.Fatalf("created %+v", ug)
+	}
+
+	_, err = client.UsergroupsCreate(ctx, "Ops Team", nil)
+	if serr, ok := slopher.AsSlackError(err); !ok || serr.Code != "name_already_exists" {
+		t.Errorf("duplicate UsergroupsCreate error = %v", err)
+	}
+
+	updated, err := client.UsergroupsUpdate(ctx, ug.ID, &slopher.UserGroupOptions{Description: "On call"})
+	if err != nil {
+		t.Fatalf("UsergroupsUpdate: %v", err)
+	}
+	if updated.UserGroup.Description != "On call" || updated.UserGroup.Name != "Ops Team" {
+		t.Errorf("updated %+v", updated.UserGroup)
+	}
+
+	members, err := client.UsergroupsUsersUpdate(ctx, ug.ID, []string{"U1", "U2"})
+	if err != nil {
+		t.Fatalf("UsergroupsUsersUpdate: %v", err)
+	}
+	if string(members.UserGroup.UserCount) != "2" {
+		t.Errorf("UserCount = %q", members.UserGroup.UserCount)
+	}
+	users, err := client.UsergroupsUsersList(ctx, ug.ID, false)
+	if err != nil {
+		t.Fatalf("UsergroupsUsersList: %v", err)
+	}
+	if !reflect.DeepEqual(users.Users, []string{"U1", "U2"}) {
+		t.Errorf("Users = %v", users.Users)
+	}
+
+	disabled, err := client.UsergroupsDisable(ctx, ug.ID)
+	if err != nil {
+		t.Fatalf("UsergroupsDisable: %v", err)
+	}
+	if !disabled.UserGroup.IsDisabled() {
+		t.Error("disabled group isn't IsDisabled")
+	}
+
+	list, err := client.UsergroupsList(ctx, true, false)
+	if err != nil {
+		t.Fatalf("UsergroupsList: %v", err)
+	}
+	if len(list.UserGroups) != 0 {
+		t.Errorf("disabled group listed: %+v", list.UserGroups)
+	}
+	list, err = client.UsergroupsList(ctx, true, true)
+	if err != nil {
+		t.Fatalf("UsergroupsList: %v", err)
+	}
+	if len(list.UserGroups) != 1 || !reflect.DeepEqual(list.UserGroups[0].Users, []string{"U1", "U2"}) {
+		t.Errorf("UserGroups = %+v", list.UserGroups)
+	}
+
+	enabled, err := client.UsergroupsEnable(ctx, ug.ID)
+	if err != nil {
+		t.Fatalf("UsergroupsEnable: %v", err)
+	}
+	if enabled.UserGroup.IsDisabled() {
+		t.Error("enabled group is IsDisabled")
+	}
+}
+
+func entityNames(entities []*slopher.Entity) []string {
+	names := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		names = append(names, entity.Name)
+	}
+	return names
+}
+
+func TestUserGroupStateTracking(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddUser(&slopher.User{ID: "U1", Name: "alice"})
+	srv.AddUser(&slopher.User{ID: "U2", Name: "bob"})
+	srv.AddUserGroup(&slopher.UserGroup{ID: "S1", Handle: "ops",
+		Users: []string{slacktest.DefaultSelfID, "U1"}})
+	srv.AddUserGroup(&slopher.UserGroup{ID: "S2", Handle: "devs",
+		Users: []string{"U2"}})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	state_mgr := slopher.GetDefaultStateManager()
+	ctx := client.NewContext(context.Background())
+	ctx = slopher.NewContextForStateManager(ctx, state_mgr)
+
+	rtm, err := slopher.NewRTMProcessor(ctx)
+	if err != nil {
+		t.Fatalf("NewRTMProcessor: %v", err)
+	}
+
+	if !state_mgr.InUserGroup("S1") || state_mgr.InUserGroup("S2") {
+		t.Errorf("SelfUserGroups = %v", state_mgr.SelfUserGroups)
+	}
+
+	mentioned := state_mgr.ResolveSubteamMentions("hey <!subteam^S1|@ops> and <!subteam^S3>")
+	if len(mentioned) != 2 || len(mentioned["S3"]) != 0 {
+		t.Errorf("resolved %v", mentioned)
+	}
+	if names := entityNames(mentioned["S1"]); !reflect.DeepEqual(names, []string{"slacktest-bot", "alice"}) {
+		t.Errorf("S1 members = %v", names)
+	}
+
+	events := make(chan string, 2)
+	rtm.OnSubteamSelfAdded(func(ctx context.Context, _msg slopher.RTMMessage) {
+		events <- "added " + _msg.(*slopher.RTMSubteamSelfMessage).SubteamID
+	})
+	rtm.OnSubteamSelfRemoved(func(ctx context.Context, _msg slopher.RTMMessage) {
+		events <- "removed " + _msg.(*slopher.RTMSubteamSelfMessage).SubteamID
+	})
+
+	ctx = rtm.NewContext(ctx)
+	if err := rtm.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer rtm.Stop(ctx, false)
+	if err := srv.WaitForRTMConnection(5 * time.Second); err != nil {
+		t.Fatalf("WaitForRTMConnection: %v", err)
+	}
+
+	waitForEvent := func(want string) {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("event = %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %q event", want)
+		}
+	}
+
+	srv.SendEvent(map[string]interface{}{"type": "subteam_self_added", "subteam_id": "S2"})
+	waitForEvent("added S2")
+	if !state_mgr.InUserGroup("S2") {
+		t.Error("not in S2 after subteam_self_added")
+	}
+	members := state_mgr.ResolveSubteamMentions("<!subteam^S2>")["S2"]
+	if names := entityNames(members); !reflect.DeepEqual(names, []string{"bob", "slacktest-bot"}) {
+		t.Errorf("S2 members = %v", names)
+	}
+
+	srv.SendEvent(map[string]interface{}{"type": "subteam_self_removed", "subteam_id": "S1"})
+	waitForEvent("removed S1")
+	if state_mgr.InUserGroup("S1") {
+		t.Error("still in S1 after subteam_self_removed")
+	}
+	if names := entityNames(state_mgr.UserGroupMembers("S1")); !reflect.DeepEqual(names, []string{"alice"}) {
+		t.Errorf("S1 members = %v", names)
+	}
+}
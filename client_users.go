@@ -0,0 +1,148 @@
+package slopher
+
+import "golang.org/x/net/context"
+
+const (
+	PresenceAuto = "auto"
+	PresenceAway = "away"
+)
+
+// UserResponse is returned by the users.* methods that return a single
+// user.
+type UserResponse struct {
+	baseAPIResponse
+
+	User *User `json:"user"`
+}
+
+func (self *Client) UsersInfo(ctx context.Context, user_id string) (*UserResponse, error) {
+	resp := &UserResponse{}
+	args := APIArgs{"user": user_id}
+
+	err := self.apiCall(ctx, "users.info", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (self *Client) UsersLookupByEmail(ctx context.Context, email string) (*UserResponse, error) {
+	resp := &UserResponse{}
+	args := APIArgs{"email": email}
+
+	err := self.apiCall(ctx, "users.lookupByEmail", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** users.list
+ */
+type UsersListResponse struct {
+	baseAPIResponse
+
+	Members []*User `json:"members"`
+}
+
+func (self *UsersListResponse) numItems() int {
+	return len(self.Members)
+}
+
+func (self *UsersListResponse) appendItems(page PagedResponse) {
+	self.Members = append(self.Members, page.(*UsersListResponse).Members...)
+}
+
+func (self *UsersListResponse) truncateItems(n int) {
+	self.Members = self.Members[:n]
+}
+
+// UsersList returns all users, following pagination.
+func (self *Client) UsersList(ctx context.Context) (*UsersListResponse, error) {
+	resp, err := self.UsersListPages(nil).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*UsersListResponse), nil
+}
+
+func (self *Client) UsersListPages(args APIArgs) *Paginator {
+	return self.newPaginator("users.list", args, func() PagedResponse {
+		return &UsersListResponse{}
+	})
+}
+
+/*
+** Presence
+ */
+type UsersGetPresenceResponse struct {
+	baseAPIResponse
+
+	Presence string `json:"presence"`
+	// The rest are only returned for the token's own user
+	Online          bool      `json:"online"`
+	AutoAway        bool      `json:"auto_away"`
+	ManualAway      bool      `json:"manual_away"`
+	ConnectionCount int       `json:"connection_count"`
+	LastActivity    EpochTime `json:"last_activity"`
+}
+
+func (self *Client) UsersGetPresence(ctx context.Context, user_id string) (*UsersGetPresenceResponse, error) {
+	resp := &UsersGetPresenceResponse{}
+	args := APIArgs{"user": user_id}
+
+	err := self.apiCall(ctx, "users.getPresence", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+type UsersSetPresenceResponse struct {
+	baseAPIResponse
+}
+
+// UsersSetPresence sets the token user's presence to PresenceAuto or
+// PresenceAway. This needs a user token.
+func (self *Client) UsersSetPresence(ctx context.Context, presence string) (*UsersSetPresenceResponse, error) {
+	resp := &UsersSetPresenceResponse{}
+	args := APIArgs{"presence": presence}
+
+	err := self.apiCall(ctx, "users.setPresence", args, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+/*
+** users.conversations
+ */
+
+// UsersConversations returns all the conversations user_id is a member
+// of, following pagination. An empty user_id means the token's user.
+// args may include "types" and "exclude_archived", as for
+// ConversationsList.
+func (self *Client) UsersConversations(ctx context.Context, user_id string, args APIArgs) (*ConversationsListResponse, error) {
+	resp, err := self.UsersConversationsPages(user_id, args).Collect(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*ConversationsListResponse), nil
+}
+
+func (self *Client) UsersConversationsPages(user_id string, args APIArgs) *Paginator {
+	if user_id != "" {
+		args = withArgs(args, "user", user_id)
+	}
+	return self.newPaginator("users.conversations", args, func() PagedResponse {
+		return &ConversationsListResponse{}
+	})
+}
@@ -0,0 +1,100 @@
+package slopher_test
+
+import (
+	"testing"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestUsersAPI(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	for _, user := range []*slopher.User{
+		{ID: "U1", Name: "alice", Profile: &slopher.UserProfile{Email: "alice@example.com"}},
+		{ID: "U2", Name: "bob"},
+		{ID: "U3", Name: "carol"},
+	} {
+		srv.AddUser(user)
+	}
+	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general", IsChannel: true,
+		Members: []string{"U1", "U2"}})
+	srv.AddChannel(&slopher.Channel{ID: "C2", Name: "random", IsChannel: true,
+		Members: []string{"U2"}})
+	srv.AddIM(&slopher.IM{ID: "D1", UserID: "U1"})
+
+	// Only a user token, which bot-token methods fall back to
+	client := slopher.NewClient(srv.APIURL(), "", nil)
+	client.UserToken = "xoxp-test"
+	ctx := context.Background()
+
+	info, err := client.UsersInfo(ctx, "U2")
+	if err != nil {
+		t.Fatalf("UsersInfo: %v", err)
+	}
+	if info.User == nil || info.User.Name != "bob" {
+		t.Errorf("User = %+v", info.User)
+	}
+	if _, err := client.UsersInfo(ctx, "U9"); !slopher.IsNotFound(err) {
+		t.Errorf("UsersInfo(U9) = %v, want not found", err)
+	}
+
+	found, err := client.UsersLookupByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("UsersLookupByEmail: %v", err)
+	}
+	if found.User == nil || found.User.ID != "U1" {
+		t.Errorf("User = %+v", found.User)
+	}
+
+	// Two users per page
+	pages := client.UsersListPages(slopher.APIArgs{"limit": "2"})
+	var per_page []int
+	for pages.Next(ctx) {
+		per_page = append(per_page, len(pages.Page().(*slopher.UsersListResponse).Members))
+	}
+	if err := pages.Err(); err != nil {
+		t.Fatalf("UsersListPages: %v", err)
+	}
+	if len(per_page) != 2 || per_page[0] != 2 || per_page[1] != 1 {
+		t.Errorf("page sizes = %v", per_page)
+	}
+	all, err := client.UsersList(ctx)
+	if err != nil {
+		t.Fatalf("UsersList: %v", err)
+	}
+	if len(all.Members) != 3 || all.Members[2].Name != "carol" {
+		t.Errorf("Members = %+v", all.Members)
+	}
+
+	if _, err := client.UsersSetPresence(ctx, slopher.PresenceAway); err != nil {
+		t.Fatalf("UsersSetPresence: %v", err)
+	}
+	presence, err := client.UsersGetPresence(ctx, slacktest.DefaultSelfID)
+	if err != nil {
+		t.Fatalf("UsersGetPresence: %v", err)
+	}
+	if presence.Presence != "away" || !presence.ManualAway {
+		t.Errorf("own presence = %+v", presence)
+	}
+	presence, err = client.UsersGetPresence(ctx, "U1")
+	if err != nil {
+		t.Fatalf("UsersGetPresence: %v", err)
+	}
+	if presence.Presence != "active" {
+		t.Errorf("U1 presence = %q", presence.Presence)
+	}
+
+	convs, err := client.UsersConversations(ctx, "U1", slopher.APIArgs{"types": "public_channel,im"})
+	if err != nil {
+		t.Fatalf("UsersConversations: %v", err)
+	}
+	var ids []string
+	for _, conv := range convs.Channels {
+		ids = append(ids, conv.ID)
+	}
+	if len(ids) != 2 || ids[0] != "C1" || ids[1] != "D1" {
+		t.Errorf("U1's conversations = %v", ids)
+	}
+}
@@ -23,11 +23,31 @@ func (t *EpochTime) UnmarshalJSON(b []byte) (err error) {
 		}
 	}
 
-	*t = EpochTime(time.Unix(sec, 0))
+	// Slack sends 0 for "not set", so keep that as the zero time.
+	if sec == 0 {
+		*t = EpochTime{}
+	} else {
+		*t = EpochTime(time.Unix(sec, 0))
+	}
 
 	return
 }
 
+// IsZero returns true if the time wasn't set (Slack sent 0).
+func (t EpochTime) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+// MarshalJSON writes the time as Slack does, in seconds since the epoch.
+// Without it, EpochTime (which doesn't have time.Time's methods) encodes
+// as {}, which UnmarshalJSON can't read back.
+func (t EpochTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+	return []byte(strconv.FormatInt(time.Time(t).Unix(), 10)), nil
+}
+
 type BotIcons struct {
 	Image48 string `json:"image_48"`
 }
@@ -152,11 +172,13 @@ type BaseMessage struct {
 	SubType string `json:"subtype,omitempty"`
 
 	TS          string             `json:"ts,omitempty"`
+	ThreadTS    string             `json:"thread_ts,omitempty"`
 	UserID      string             `json:"user,omitempty"`
 	ChannelID   string             `json:"channel,omitempty"`
 	Text        string             `json:"text,omitempty"`
 	Attachments []Attachment       `json:"attachments,omitempty"`
 	Edited      *MessageEditedInfo `json:"edited,omitempty"`
+	Reactions   []Reaction         `json:"reactions,omitempty"`
 }
 
 type Message struct {
@@ -277,6 +299,35 @@ type Comment struct {
 	Comment   string    `json:"comment"`
 }
 
+type Reaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// ItemRef refers to something that can be reacted to, pinned or
+// starred: a message, a file or a file comment.
+type ItemRef struct {
+	Type          string `json:"type"`
+	ChannelID     string `json:"channel,omitempty"`
+	TS            string `json:"ts,omitempty"`
+	FileID        string `json:"file,omitempty"`
+	FileCommentID string `json:"file_comment,omitempty"`
+}
+
+func NewMessageItemRef(channel_id, ts string) *ItemRef {
+	return &ItemRef{Type: "message", ChannelID: channel_id, TS: ts}
+}
+
+func NewFileItemRef(file_id string) *ItemRef {
+	return &ItemRef{Type: "file", FileID: file_id}
+}
+
+func NewFileCommentItemRef(file_id, file_comment_id string) *ItemRef {
+	return &ItemRef{Type: "file_comment", FileID: file_id,
+		FileCommentID: file_comment_id}
+}
+
 type SharedFile struct {
 	ID                 string    `json:"id"`
 	Created            EpochTime `json:"created"`
@@ -317,6 +368,8 @@ type SharedFile struct {
 	GroupIDs           []string  `json:"groups"`
 	IMIDs              []string  `json:"ims"`
 	CommentsCount      int64     `json:"comments_count"`
+
+	Reactions []Reaction `json:"reactions,omitempty"`
 }
 
 // file_share
@@ -393,33 +446,54 @@ type FileCommentSubType struct {
 ** End of Message SubTypes
  */
 
+// Retention types, for TeamPrefs
+const (
+	RetentionKeepAll = 0
+	// Keep everything but the edit history
+	RetentionKeepAllNoEdits = 1
+	// Keep for RetentionDuration days
+	RetentionDays = 2
+)
+
+// TeamPrefs are the workspace's settings. The WhoCan* policies are
+// "regular" (anyone but guests), "ra" (restricted accounts too),
+// "admin" or "owner".
 type TeamPrefs struct {
-	AllowMessageDeletion bool `json:"allow_message_deletion"`
-	/*
-	   "compliance_export_start": 0,
-	   "default_channels": [
-	       "<CHANNEL_ID>"
-	   ],
-	   "display_real_names": true,
-	   "dm_retention_duration": 0,
-	   "dm_retention_type": 0,
-	   "group_retention_duration": 0,
-	   "group_retention_type": 0,
-	   "hide_referers": true,
-	   "msg_edit_window_mins": -1,
-	   "require_at_for_mention": 0,
-	   "retention_duration": 0,
-	   "retention_type": 0,
-	   "warn_before_at_channel": "always",
-	   "who_can_archive_channels": "regular",
-	   "who_can_at_channel": "ra",
-	   "who_can_at_everyone": "regular",
-	   "who_can_create_channels": "regular",
-	   "who_can_create_groups": "ra",
-	   "who_can_kick_channels": "admin",
-	   "who_can_kick_groups": "regular",
-	   "who_can_post_general": "ra"
-	*/
+	AllowMessageDeletion  bool      `json:"allow_message_deletion"`
+	ComplianceExportStart EpochTime `json:"compliance_export_start"`
+	DefaultChannels       []string  `json:"default_channels"`
+	DisplayRealNames      bool      `json:"display_real_names"`
+	HideReferers          bool      `json:"hide_referers"`
+	// -1 if messages can always be edited
+	MsgEditWindowMins   int    `json:"msg_edit_window_mins"`
+	RequireAtForMention int    `json:"require_at_for_mention"`
+	WarnBeforeAtChannel string `json:"warn_before_at_channel"`
+
+	// Retention, by *_retention_type and, for RetentionDays, days
+	RetentionType          int `json:"retention_type"`
+	RetentionDuration      int `json:"retention_duration"`
+	GroupRetentionType     int `json:"group_retention_type"`
+	GroupRetentionDuration int `json:"group_retention_duration"`
+	DMRetentionType        int `json:"dm_retention_type"`
+	DMRetentionDuration    int `json:"dm_retention_duration"`
+
+	WhoCanArchiveChannels string `json:"who_can_archive_channels"`
+	WhoCanAtChannel       string `json:"who_can_at_channel"`
+	WhoCanAtEveryone      string `json:"who_can_at_everyone"`
+	WhoCanCreateChannels  string `json:"who_can_create_channels"`
+	WhoCanCreateGroups    string `json:"who_can_create_groups"`
+	WhoCanKickChannels    string `json:"who_can_kick_channels"`
+	WhoCanKickGroups      string `json:"who_can_kick_groups"`
+	WhoCanPostGeneral     string `json:"who_can_post_general"`
+}
+
+// MsgEditWindow returns how long messages can be edited for, and false
+// if there's no limit.
+func (self *TeamPrefs) MsgEditWindow() (time.Duration, bool) {
+	if self.MsgEditWindowMins < 0 {
+		return 0, false
+	}
+	return time.Duration(self.MsgEditWindowMins) * time.Minute, true
 }
 
 type TeamIcons struct {
@@ -435,6 +509,8 @@ type TeamIcons struct {
 type Team struct {
 	Domain            string     `json:"domain"`
 	EmailDomain       string     `json:"email_domain"`
+	EnterpriseID      string     `json:"enterprise_id,omitempty"`
+	EnterpriseName    string     `json:"enterprise_name,omitempty"`
 	Icon              *TeamIcons `json:"icon,omitempty"`
 	ID                string     `json:"id"`
 	MsgEditWindowMins int        `json:"msg_edit_window_mins"`
@@ -564,19 +640,47 @@ type Self struct {
 
 type UserProfile struct {
 	Email              string `json:"email"`
-	FirstName          string `json:"first_key"`
+	FirstName          string `json:"first_name"`
 	Image_192          string `json:"image_192"`
 	Image_24           string `json:"image_24"`
 	Image_32           string `json:"image_32"`
 	Image_48           string `json:"image_48"`
 	Image_72           string `json:"image_72"`
 	ImageOriginal      string `json:"image_original"`
-	LastName           string `json:"last_key"`
+	LastName           string `json:"last_name"`
 	Phone              string `json:"phone"`
 	RealName           string `json:"real_name"`
 	RealNameNormalized string `json:"real_name_normalized"`
 	Skype              string `json:"skype"`
 	Title              string `json:"title"`
+
+	DisplayName           string `json:"display_name"`
+	DisplayNameNormalized string `json:"display_name_normalized"`
+
+	StatusText  string `json:"status_text"`
+	StatusEmoji string `json:"status_emoji"`
+	// Zero (see EpochTime.IsZero) if the status doesn't expire
+	StatusExpiration EpochTime `json:"status_expiration"`
+
+	// Custom profile fields, by field ID. See TeamProfileGet for what
+	// they are.
+	Fields UserProfileFields `json:"fields,omitempty"`
+}
+
+type UserProfileField struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+type UserProfileFields map[string]*UserProfileField
+
+// Slack sends [] rather than {} when there are no fields.
+func (self *UserProfileFields) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		*self = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]*UserProfileField)(self))
 }
 
 type User struct {
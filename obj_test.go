@@ -0,0 +1,69 @@
+package slopher
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEpochTimeRoundTrip(t *testing.T) {
+	channel := &Channel{ID: "C1", Created: EpochTime(time.Unix(1700000000, 0))}
+
+	data, err := json.Marshal(channel)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	if string(fields["created"]) != "1700000000" {
+		t.Errorf("created encoded as %s", fields["created"])
+	}
+
+	var decoded Channel
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := time.Time(decoded.Created); got.Unix() != 1700000000 {
+		t.Errorf("created decoded as %v", got)
+	}
+}
+
+func TestEpochTimeDecodesStrings(t *testing.T) {
+	var tm EpochTime
+	if err := json.Unmarshal([]byte(`"1392163200"`), &tm); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if time.Time(tm).Unix() != 1392163200 {
+		t.Errorf("decoded as %v", time.Time(tm))
+	}
+	if err := json.Unmarshal([]byte(`"soon"`), &tm); err == nil {
+		t.Error("decoded a non-number")
+	}
+}
+
+func TestEpochTimeZero(t *testing.T) {
+	var profile UserProfile
+	if err := json.Unmarshal([]byte(`{"status_text": "lunch", "status_expiration": 0}`), &profile); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !profile.StatusExpiration.IsZero() || !time.Time(profile.StatusExpiration).IsZero() {
+		t.Errorf("StatusExpiration 0 decoded as %v", time.Time(profile.StatusExpiration))
+	}
+
+	if err := json.Unmarshal([]byte(`{"status_expiration": 1700000000}`), &profile); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if profile.StatusExpiration.IsZero() {
+		t.Error("StatusExpiration 1700000000 decoded as zero")
+	}
+
+	data, _ := json.Marshal(&UserGroup{ID: "S1"})
+	var ug UserGroup
+	if err := json.Unmarshal(data, &ug); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if ug.IsDisabled() {
+		t.Error("zero date_delete round-tripped as disabled")
+	}
+}
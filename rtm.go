@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -23,6 +23,29 @@ var WSMessageTypeToName = map[int]string{
 
 type RTMHook func(context.Context, RTMMessage)
 
+// WSConn is the part of *websocket.Conn that RTMProcessor uses.
+type WSConn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(msgtype int, data []byte) error
+	WriteControl(msgtype int, data []byte, deadline time.Time) error
+	Close() error
+}
+
+// WSDialer opens the RTM websocket connection.
+type WSDialer interface {
+	Dial(url string, hdr http.Header) (WSConn, error)
+}
+
+type defaultWSDialer struct{}
+
+func (defaultWSDialer) Dial(url string, hdr http.Header) (WSConn, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (self *RTMStartResponse) SetRaw(data []byte) {
 	self.raw = data
 }
@@ -32,9 +55,13 @@ func (self *RTMStartResponse) GetRaw() []byte {
 }
 
 type RTMProcessor struct {
+	// Guards ws, seq_id, Running and Stopping
+	mutex         sync.Mutex
 	done          chan struct{}
-	ws            *websocket.Conn
-	log           *log.Logger
+	ws            WSConn
+	dialer        WSDialer
+	log           Logger
+	redactor      *redactor
 	hooks         map[string][]RTMHook
 	seq_id        int64
 	WSUrl         string
@@ -70,10 +97,6 @@ func NewRTMProcessor(ctx context.Context) (*RTMProcessor, error) {
 		return nil, err
 	}
 
-	if !rtm_resp.Ok {
-		return nil, fmt.Errorf("Received !Ok response: %s", string(rtm_resp.raw))
-	}
-
 	if rtm_resp.WSUrl == "" {
 		return nil, errors.New("Websocket URL is empty")
 	}
@@ -81,9 +104,11 @@ func NewRTMProcessor(ctx context.Context) (*RTMProcessor, error) {
 	rtm := &RTMProcessor{
 		done:          make(chan struct{}),
 		log:           cli.log,
+		redactor:      cli.redactor,
 		seq_id:        1,
 		WSUrl:         rtm_resp.WSUrl,
 		AutoReconnect: true,
+		dialer:        cli.wsDialer,
 	}
 
 	ctx = rtm.NewContext(ctx)
@@ -121,10 +146,9 @@ func runRTMHooks(ctx context.Context, name string, msg RTMMessage) {
 	}
 }
 
-func (self *RTMProcessor) wsConnect(ctx context.Context) (*websocket.Conn, error) {
+func (self *RTMProcessor) wsConnect(ctx context.Context) (WSConn, error) {
 	var hdr http.Header
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.Dial(self.WSUrl, hdr)
+	conn, err := self.dialer.Dial(self.WSUrl, hdr)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't connect to %s: %s",
 			self.WSUrl, err)
@@ -132,7 +156,7 @@ func (self *RTMProcessor) wsConnect(ctx context.Context) (*websocket.Conn, error
 	return conn, nil
 }
 
-func (self *RTMProcessor) wsReconnect(ctx context.Context) (*websocket.Conn, error) {
+func (self *RTMProcessor) wsReconnect(ctx context.Context) (WSConn, error) {
 	cli, ok := ClientFromContext(ctx)
 	if !ok {
 		return nil, errors.New("No Client found in context")
@@ -147,14 +171,12 @@ func (self *RTMProcessor) wsReconnect(ctx context.Context) (*websocket.Conn, err
 	var err error
 
 	for {
-		if self.Stopping {
+		if self.isStopping() {
 			return nil, nil
 		}
-		self.log.Print("Attempting reconnect (start.rtm)...")
+		self.log.Log(LogInfo, "Attempting RTM reconnect (rtm.start)")
 		if rtm_resp, err := cli.RTMStart(ctx); err == nil {
-			if !rtm_resp.Ok {
-				err = errors.New("Received !Ok response")
-			} else if rtm_resp.WSUrl == "" {
+			if rtm_resp.WSUrl == "" {
 				err = errors.New("Websocket URL is empty")
 			} else {
 				self.WSUrl = rtm_resp.WSUrl
@@ -168,8 +190,8 @@ func (self *RTMProcessor) wsReconnect(ctx context.Context) (*websocket.Conn, err
 		if delay > 30 {
 			delay = 30
 		}
-		self.log.Print("Reconnect failed (will try again after %s): %s",
-			delay, err)
+		self.log.Log(LogWarn, "RTM reconnect failed, will try again",
+			"delay", delay, "error", err)
 		time.Sleep(delay)
 	}
 }
@@ -180,8 +202,8 @@ func (self *RTMProcessor) processMessage(ctx context.Context, msgtype int, data
 		smsg = "Unknown"
 	}
 
-	self.log.Printf("Got message (%s) from WebSocket: %s\n", smsg,
-		data)
+	self.log.Log(LogDebug, "Got message from WebSocket", "type", smsg,
+		"data", self.redactor.redactBody(data))
 
 	if msgtype != websocket.TextMessage {
 		return nil
@@ -193,15 +215,15 @@ func (self *RTMProcessor) processMessage(ctx context.Context, msgtype int, data
 	}{}
 
 	if err := json.Unmarshal(data, mtype); err != nil {
-		self.log.Printf("Error decoding Websocket message: %s\n",
-			err)
+		self.log.Log(LogError, "Error decoding WebSocket message",
+			"error", err)
 		return err
 	}
 
 	obj_ptr, ok := rtmMessageTypeToObj[mtype.Type]
 	if !ok {
-		self.log.Printf("Warning: ignoring unknown message type: %s\n",
-			mtype.Type)
+		self.log.Log(LogWarn, "Ignoring unknown RTM message type",
+			"type", mtype.Type)
 		return nil
 	}
 	ref_val := reflect.ValueOf(obj_ptr).Elem()
@@ -210,8 +232,8 @@ func (self *RTMProcessor) processMessage(ctx context.Context, msgtype int, data
 	nobj := nobj_val.Interface().(RTMMessage)
 	nobj.SetRaw(data)
 	if err := json.Unmarshal(data, nobj); err != nil {
-		self.log.Printf("Error decoding Websocket message: %s\n",
-			err)
+		self.log.Log(LogError, "Error decoding WebSocket message",
+			"error", err)
 		return err
 	}
 
@@ -220,13 +242,32 @@ func (self *RTMProcessor) processMessage(ctx context.Context, msgtype int, data
 	return nil
 }
 
+func (self *RTMProcessor) isStopping() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.Stopping
+}
+
+func (self *RTMProcessor) setWS(conn WSConn) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.ws = conn
+}
+
+func (self *RTMProcessor) getWS() WSConn {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.ws
+}
+
 func (self *RTMProcessor) Start(ctx context.Context) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
 	if self.Running {
 		return errors.New("RTMProcessor already running")
 	}
 
-	var conn *websocket.Conn
-
 	conn, err := self.wsConnect(ctx)
 	if err != nil {
 		return err
@@ -237,15 +278,15 @@ func (self *RTMProcessor) Start(ctx context.Context) error {
 
 	go func() {
 		for {
-			if self.Stopping {
+			if self.isStopping() {
 				break
 			}
 
 			msgtype, data, err := conn.ReadMessage()
 			if err != nil {
-				self.log.Printf("Closing RTM connection to %s due to "+
-					"read error: %s", self.WSUrl, err)
-				self.ws = nil
+				self.log.Log(LogWarn, "Closing RTM connection due to read error",
+					"url", self.WSUrl, "error", err)
+				self.setWS(nil)
 				conn.Close()
 				if !self.AutoReconnect {
 					break
@@ -255,6 +296,7 @@ func (self *RTMProcessor) Start(ctx context.Context) error {
 				if err != nil {
 					break
 				}
+				self.setWS(conn)
 				continue
 			}
 
@@ -262,9 +304,12 @@ func (self *RTMProcessor) Start(ctx context.Context) error {
 				self.processMessage(ctx, msgtype, data)
 			}()
 		}
+		self.mutex.Lock()
 		self.Running = false
-		if self.ws != nil {
-			self.ws.Close()
+		ws := self.ws
+		self.mutex.Unlock()
+		if ws != nil {
+			ws.Close()
 		}
 		close(self.done)
 	}()
@@ -276,16 +321,21 @@ func (self *RTMProcessor) Done(ctx context.Context) chan struct{} {
 }
 
 func (self *RTMProcessor) Stop(ctx context.Context, wait bool) {
+	self.mutex.Lock()
 	if !self.Running {
+		self.mutex.Unlock()
 		return
 	}
-
 	self.Stopping = true
-	self.log.Printf("Writing Close message\n")
-	if self.ws != nil {
-		err := self.ws.WriteControl(websocket.CloseMessage, make([]byte, 0), time.Time{})
+	ws := self.ws
+	self.mutex.Unlock()
+
+	self.log.Log(LogDebug, "Writing Close message")
+	if ws != nil {
+		err := ws.WriteControl(websocket.CloseMessage, make([]byte, 0), time.Time{})
 		if err != nil {
-			self.log.Printf("Failed to write close message: %s\n", err)
+			self.log.Log(LogError, "Failed to write close message",
+				"error", err)
 			return
 		}
 	}
@@ -333,26 +383,96 @@ func (self *RTMProcessor) OnIMCreated(fn RTMHook) {
 	self.addHook("im_created", fn)
 }
 
+// Hooks are passed an *RTMUserChangedMessage, after StateManager has
+// updated the user.
+func (self *RTMProcessor) OnUserChange(fn RTMHook) {
+	self.addHook("user_change", fn)
+}
+
+// Hooks are passed an *RTMSubteamMessage.
+func (self *RTMProcessor) OnSubteamCreated(fn RTMHook) {
+	self.addHook("subteam_created", fn)
+}
+
+func (self *RTMProcessor) OnSubteamUpdated(fn RTMHook) {
+	self.addHook("subteam_updated", fn)
+}
+
+// Hooks are passed an *RTMSubteamMembersChangedMessage.
+func (self *RTMProcessor) OnSubteamMembersChanged(fn RTMHook) {
+	self.addHook("subteam_members_changed", fn)
+}
+
+// Hooks are passed an *RTMSubteamSelfMessage, after StateManager has
+// updated SelfUserGroups.
+func (self *RTMProcessor) OnSubteamSelfAdded(fn RTMHook) {
+	self.addHook("subteam_self_added", fn)
+}
+
+func (self *RTMProcessor) OnSubteamSelfRemoved(fn RTMHook) {
+	self.addHook("subteam_self_removed", fn)
+}
+
+// Hooks are passed an *RTMEmojiChangedMessage.
+func (self *RTMProcessor) OnEmojiChanged(fn RTMHook) {
+	self.addHook("emoji_changed", fn)
+}
+
+// Hooks are passed an *RTMReactionMessage.
+func (self *RTMProcessor) OnReactionAdded(fn RTMHook) {
+	self.addHook("reaction_added", fn)
+}
+
+func (self *RTMProcessor) OnReactionRemoved(fn RTMHook) {
+	self.addHook("reaction_removed", fn)
+}
+
+// Hooks are passed an *RTMPinMessage.
+func (self *RTMProcessor) OnPinAdded(fn RTMHook) {
+	self.addHook("pin_added", fn)
+}
+
+func (self *RTMProcessor) OnPinRemoved(fn RTMHook) {
+	self.addHook("pin_removed", fn)
+}
+
+// Hooks are passed an *RTMStarMessage.
+func (self *RTMProcessor) OnStarAdded(fn RTMHook) {
+	self.addHook("star_added", fn)
+}
+
+func (self *RTMProcessor) OnStarRemoved(fn RTMHook) {
+	self.addHook("star_removed", fn)
+}
+
 func (self *RTMProcessor) sendMessage(ctx context.Context, msg *Message) error {
 	bytes, err := json.Marshal(msg)
 	if err != nil {
-		self.log.Printf("Error converting message to json %+v: %s\n",
-			*msg, err)
+		self.log.Log(LogError, "Error converting message to JSON",
+			"message", self.redactor.redactBody([]byte(fmt.Sprintf("%+v", *msg))),
+			"error", err)
 		return err
 	}
 
-	self.log.Printf("Sending to WS Socket: %s\n", bytes)
-	err = self.ws.WriteMessage(websocket.TextMessage, bytes)
+	self.log.Log(LogDebug, "Sending to WebSocket",
+		"data", self.redactor.redactBody(bytes))
+	ws := self.getWS()
+	if ws == nil {
+		return errors.New("RTM websocket is not connected")
+	}
+	err = ws.WriteMessage(websocket.TextMessage, bytes)
 	if err != nil {
-		self.log.Printf("Got error sending %+v: %s\n", *msg, err)
+		self.log.Log(LogError, "Error sending to WebSocket",
+			"data", self.redactor.redactBody(bytes), "error", err)
 	}
 	return err
 }
 
 func (self *RTMProcessor) SendWSMessage(ctx context.Context, msg *Message) error {
-	// Race
+	self.mutex.Lock()
 	seq_id := self.seq_id
 	self.seq_id++
+	self.mutex.Unlock()
 
 	msg.Id = &seq_id
 	return self.sendMessage(ctx, msg)
@@ -1,7 +1,6 @@
 package slopher
 
 import (
-	"fmt"
 	"golang.org/x/net/context"
 )
 
@@ -21,6 +20,21 @@ var rtmMessageTypeToObj = map[string]RTMMessage{
 	"group_joined":    &RTMGroupJoinedMessage{},
 	"im_created":      &RTMIMCreatedMessage{},
 	"user_change":     &RTMUserChangedMessage{},
+
+	"reaction_added":   &RTMReactionMessage{},
+	"reaction_removed": &RTMReactionMessage{},
+	"pin_added":        &RTMPinMessage{},
+	"pin_removed":      &RTMPinMessage{},
+	"star_added":       &RTMStarMessage{},
+	"star_removed":     &RTMStarMessage{},
+
+	"subteam_created":         &RTMSubteamMessage{},
+	"subteam_updated":         &RTMSubteamMessage{},
+	"subteam_members_changed": &RTMSubteamMembersChangedMessage{},
+	"subteam_self_added":      &RTMSubteamSelfMessage{},
+	"subteam_self_removed":    &RTMSubteamSelfMessage{},
+
+	"emoji_changed": &RTMEmojiChangedMessage{},
 }
 
 var rtmMessageSubTypeHooks = []string{
@@ -61,7 +75,10 @@ func (self *RTMChannelMessage) Process(ctx context.Context) {
 		return
 	}
 
-	fmt.Printf("Dropping subtyped message: %s %+v\n", self.raw, *self)
+	if rtm, ok := RTMProcessorFromContext(ctx); ok {
+		rtm.log.Log(LogDebug, "Dropping subtyped message",
+			"subtype", self.SubType, "data", rtm.redactor.redactBody(self.raw))
+	}
 
 	return
 }
@@ -201,3 +218,131 @@ type RTMUserChangedMessage struct {
 func (self *RTMUserChangedMessage) Process(ctx context.Context) {
 	runRTMHooks(ctx, self.Type, self)
 }
+
+/*
+** Reaction added or removed
+ */
+type RTMReactionMessage struct {
+	rawJSON
+
+	Type     string   `json:"type"`
+	UserID   string   `json:"user"`
+	Reaction string   `json:"reaction"`
+	ItemUser string   `json:"item_user"`
+	Item     *ItemRef `json:"item"`
+	EventTS  string   `json:"event_ts"`
+}
+
+func (self *RTMReactionMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** Pin added or removed
+ */
+type RTMPinMessage struct {
+	rawJSON
+
+	Type      string      `json:"type"`
+	UserID    string      `json:"user"`
+	ChannelID string      `json:"channel_id"`
+	Item      *PinnedItem `json:"item"`
+	// Whether any pins are left, for pin_removed
+	HasPins bool   `json:"has_pins"`
+	EventTS string `json:"event_ts"`
+}
+
+func (self *RTMPinMessage) Process(ctx context.Context) {
+	if self.Item != nil && self.Item.ChannelID == "" {
+		self.Item.ChannelID = self.ChannelID
+	}
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** Star added or removed
+ */
+type RTMStarMessage struct {
+	rawJSON
+
+	Type    string       `json:"type"`
+	UserID  string       `json:"user"`
+	Item    *StarredItem `json:"item"`
+	EventTS string       `json:"event_ts"`
+}
+
+func (self *RTMStarMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** User group (subteam) created or updated
+ */
+type RTMSubteamMessage struct {
+	rawJSON
+
+	Type    string     `json:"type"`
+	Subteam *UserGroup `json:"subteam"`
+}
+
+func (self *RTMSubteamMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** User group members changed
+ */
+type RTMSubteamMembersChangedMessage struct {
+	rawJSON
+
+	Type               string    `json:"type"`
+	SubteamID          string    `json:"subteam_id"`
+	TeamID             string    `json:"team_id"`
+	DatePreviousUpdate EpochTime `json:"date_previous_update"`
+	DateUpdate         EpochTime `json:"date_update"`
+	AddedUsers         []string  `json:"added_users"`
+	RemovedUsers       []string  `json:"removed_users"`
+}
+
+func (self *RTMSubteamMembersChangedMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** We were added to or removed from a user group
+ */
+type RTMSubteamSelfMessage struct {
+	rawJSON
+
+	Type      string `json:"type"`
+	SubteamID string `json:"subteam_id"`
+}
+
+func (self *RTMSubteamSelfMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
+
+/*
+** Custom emoji added, removed or renamed
+ */
+type RTMEmojiChangedMessage struct {
+	rawJSON
+
+	Type string `json:"type"`
+	// "add", "remove" or "rename"
+	SubType string `json:"subtype"`
+	// For add
+	Name string `json:"name"`
+	// For remove
+	Names []string `json:"names"`
+	// For rename
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+	// URL or alias, for add and rename
+	Value   string `json:"value"`
+	EventTS string `json:"event_ts"`
+}
+
+func (self *RTMEmojiChangedMessage) Process(ctx context.Context) {
+	runRTMHooks(ctx, self.Type, self)
+}
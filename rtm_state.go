@@ -2,6 +2,7 @@ package slopher
 
 import (
 	"errors"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -52,6 +53,9 @@ type Place struct {
 	IsChannel bool
 	IsIM      bool
 	IsGroup   bool
+	// Pinned items, once loaded with LoadPins. StateManager keeps them
+	// current.
+	Pins []*PinnedItem
 	*Channel
 	*Group
 	*IM
@@ -76,6 +80,133 @@ func (self *Place) SendMessage(ctx context.Context, msg *Message) error {
 	return rtm.SendWSMessage(ctx, msg)
 }
 
+// PostMessage posts msg to the place with chat.postMessage, using the
+// Client in the context rather than the RTM connection. Channel is
+// filled in.
+func (self *Place) PostMessage(ctx context.Context, msg *PostMessageRequest) (*PostChatMessageResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg.Channel = self.ID
+	return cli.PostMessage(ctx, msg)
+}
+
+// ScheduleMessage has Slack post msg to the place at the given time.
+// Channel is filled in.
+func (self *Place) ScheduleMessage(ctx context.Context, at time.Time, msg *PostMessageRequest) (*ChatScheduleMessageResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg.Channel = self.ID
+	return cli.ChatScheduleMessage(ctx, at, msg)
+}
+
+func (self *Place) UpdateMessage(ctx context.Context, ts, text string) (*ChatUpdateResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatUpdate(ctx, &UpdateMessageRequest{
+		Channel: self.ID,
+		TS:      ts,
+		Text:    text,
+	})
+}
+
+// PostEphemeral posts text that only user_id will see.
+func (self *Place) PostEphemeral(ctx context.Context, user_id, text string) (*ChatPostEphemeralResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatPostEphemeral(ctx, &PostEphemeralRequest{
+		Channel: self.ID,
+		User:    user_id,
+		Text:    text,
+	})
+}
+
+func (self *Place) MeMessage(ctx context.Context, text string) (*ChatMeMessageResponse, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ChatMeMessage(ctx, self.ID, text)
+}
+
+func (self *Place) Permalink(ctx context.Context, ts string) (string, error) {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	resp, err := cli.ChatGetPermalink(ctx, self.ID, ts)
+	if err != nil {
+		return "", err
+	}
+	return resp.Permalink, nil
+}
+
+// HasPins returns whether anything is pinned to the place, as far as
+// we know.
+func (self *Place) HasPins() bool {
+	return len(self.Pins) > 0 || (self.Group != nil && self.Group.HasPins)
+}
+
+// LoadPins fetches the place's pinned items into Pins.
+func (self *Place) LoadPins(ctx context.Context) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.PinsList(ctx, self.ID)
+	if err != nil {
+		return err
+	}
+	self.Pins = resp.Items
+	return nil
+}
+
+// Pin pins the message with the given ts to the place.
+func (self *Place) Pin(ctx context.Context, ts string) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = cli.PinsAdd(ctx, NewMessageItemRef(self.ID, ts))
+	return err
+}
+
+func (self *Place) Unpin(ctx context.Context, ts string) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = cli.PinsRemove(ctx, NewMessageItemRef(self.ID, ts))
+	return err
+}
+
+func (self *Place) addPin(item *PinnedItem) {
+	self.delPin(item.Ref())
+	self.Pins = append(self.Pins, item)
+	if self.Group != nil {
+		self.Group.HasPins = true
+	}
+}
+
+func (self *Place) delPin(ref *ItemRef) {
+	for i, pin := range self.Pins {
+		if *pin.Ref() == *ref {
+			self.Pins = append(self.Pins[:i], self.Pins[i+1:]...)
+			break
+		}
+	}
+	if self.Group != nil {
+		self.Group.HasPins = len(self.Pins) > 0
+	}
+}
+
 type RTMStateManager interface {
 	AddHooks(context.Context) error
 	RTMStart(context.Context, *RTMStartResponse) error
@@ -107,9 +238,22 @@ type StateManager struct {
 	EntitiesByName map[string]*Entity
 	PlacesByID     map[string]*Place
 	PlacesByName   map[string]*Place
+
+	UserGroupsByID     map[string]*UserGroup
+	UserGroupsByHandle map[string]*UserGroup
+	// IDs of the user groups we're in
+	SelfUserGroups map[string]bool
+
+	// Custom emoji, once loaded with LoadEmoji
+	Emoji EmojiCatalog
 }
 
 func (self *StateManager) addEntity(entity *Entity) *Entity {
+	// UpdateUser may be used without RTMStart having been called.
+	if self.EntitiesByID == nil {
+		self.EntitiesByID = make(map[string]*Entity)
+		self.EntitiesByName = make(map[string]*Entity)
+	}
 	if entity.User != nil {
 		self.EntitiesByID[entity.User.ID] = entity
 	}
@@ -167,6 +311,11 @@ func (self *StateManager) addEntityFromBot(bot *Bot) *Entity {
 }
 
 func (self *StateManager) addPlace(place *Place) *Place {
+	// As may AddConversations.
+	if self.PlacesByID == nil {
+		self.PlacesByID = make(map[string]*Place)
+		self.PlacesByName = make(map[string]*Place)
+	}
 	self.PlacesByID[place.ID] = place
 	self.PlacesByName[place.Name] = place
 
@@ -187,24 +336,36 @@ func (self *StateManager) addPlace(place *Place) *Place {
 }
 
 func (self *StateManager) addPlaceFromChannel(channel *Channel) *Place {
-	return self.addPlace(&Place{
+	return self.addPlace(newPlaceFromChannel(channel))
+}
+
+func (self *StateManager) addPlaceFromGroup(group *Group) *Place {
+	return self.addPlace(newPlaceFromGroup(group))
+}
+
+func (self *StateManager) addPlaceFromIM(im *IM) *Place {
+	return self.addPlace(self.newPlaceFromIM(im))
+}
+
+func newPlaceFromChannel(channel *Channel) *Place {
+	return &Place{
 		ID:        channel.ID,
 		Name:      "#" + channel.Name,
 		Channel:   channel,
 		IsChannel: true,
-	})
+	}
 }
 
-func (self *StateManager) addPlaceFromGroup(group *Group) *Place {
-	return self.addPlace(&Place{
+func newPlaceFromGroup(group *Group) *Place {
+	return &Place{
 		ID:      group.ID,
 		Name:    "#" + group.Name,
 		Group:   group,
 		IsGroup: true,
-	})
+	}
 }
 
-func (self *StateManager) addPlaceFromIM(im *IM) *Place {
+func (self *StateManager) newPlaceFromIM(im *IM) *Place {
 	var name string
 	// We want to use the User's name for the name of Place, if it exists.
 	if entity := self.FindEntity(im.UserID); entity != nil {
@@ -212,12 +373,169 @@ func (self *StateManager) addPlaceFromIM(im *IM) *Place {
 	} else {
 		name = im.UserID
 	}
-	return self.addPlace(&Place{
+	return &Place{
 		ID:   im.ID,
 		Name: name,
 		IM:   im,
 		IsIM: true,
-	})
+	}
+}
+
+// UpdateUser replaces what we know about a user, including its profile,
+// e.g. with one fetched by UsersInfo. user_change events are handled
+// this way. It works on a StateManager that hasn't had RTMStart called.
+func (self *StateManager) UpdateUser(user *User) *Entity {
+	// Name might have changed, so find by ID first.
+	entity := self.FindEntity(user.ID)
+
+	if entity != nil && entity.Name != user.Name {
+		// Handle name change.
+		delete(self.EntitiesByName, entity.Name)
+		entity.Name = user.Name
+		self.EntitiesByName[user.Name] = entity
+
+		// IMs are named after the user.
+		for _, place := range self.PlacesByID {
+			if place.IM != nil && place.IM.UserID == user.ID {
+				delete(self.PlacesByName, place.Name)
+				place.Name = user.Name
+				self.PlacesByName[place.Name] = place
+			}
+		}
+	}
+
+	replaced := false
+	for i, u := range self.Users {
+		if u.ID == user.ID {
+			self.Users[i] = user
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		self.Users = append(self.Users, user)
+	}
+
+	return self.addEntityFromUser(user)
+}
+
+// Keeps the known members if ug doesn't list them.
+func (self *StateManager) addUserGroup(ug *UserGroup) *UserGroup {
+	if self.UserGroupsByID == nil {
+		self.UserGroupsByID = make(map[string]*UserGroup)
+		self.UserGroupsByHandle = make(map[string]*UserGroup)
+	}
+	if old := self.UserGroupsByID[ug.ID]; old != nil {
+		delete(self.UserGroupsByHandle, old.Handle)
+		if ug.Users == nil {
+			ug.Users = old.Users
+		}
+	}
+	self.UserGroupsByID[ug.ID] = ug
+	self.UserGroupsByHandle[ug.Handle] = ug
+	return ug
+}
+
+// LoadUserGroups fetches all user groups and their members, using the
+// Client in the context.
+func (self *StateManager) LoadUserGroups(ctx context.Context) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.UsergroupsList(ctx, true, false)
+	if err != nil {
+		return err
+	}
+	for _, ug := range resp.UserGroups {
+		self.addUserGroup(ug)
+	}
+	return nil
+}
+
+// LoadEmoji fetches the custom emoji catalog into Emoji, using the
+// Client in the context. emoji_changed events keep it current after.
+func (self *StateManager) LoadEmoji(ctx context.Context) error {
+	cli, err := clientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.EmojiList(ctx)
+	if err != nil {
+		return err
+	}
+	if resp.Emoji == nil {
+		resp.Emoji = make(EmojiCatalog)
+	}
+	self.Emoji = resp.Emoji
+	return nil
+}
+
+func (self *StateManager) FindUserGroup(id string) *UserGroup {
+	return self.UserGroupsByID[id]
+}
+
+// FindUserGroupByHandle finds a group by its handle, without the "@".
+func (self *StateManager) FindUserGroupByHandle(handle string) *UserGroup {
+	return self.UserGroupsByHandle[handle]
+}
+
+// InUserGroup returns true if we're a member of the user group.
+func (self *StateManager) InUserGroup(id string) bool {
+	return self.SelfUserGroups[id]
+}
+
+// Records that we joined or left a user group, adding us to or removing
+// us from its members if we know about it.
+func (self *StateManager) setSelfInUserGroup(id string, member bool) {
+	if self.SelfUserGroups == nil {
+		self.SelfUserGroups = make(map[string]bool)
+	}
+	if member {
+		self.SelfUserGroups[id] = true
+	} else {
+		delete(self.SelfUserGroups, id)
+	}
+
+	ug := self.FindUserGroup(id)
+	if ug == nil || self.Self == nil {
+		return
+	}
+	users := make([]string, 0, len(ug.Users)+1)
+	for _, user_id := range ug.Users {
+		if user_id != self.Self.ID {
+			users = append(users, user_id)
+		}
+	}
+	if member {
+		users = append(users, self.Self.ID)
+	}
+	ug.Users = users
+}
+
+// UserGroupMembers returns the known members of a user group.
+func (self *StateManager) UserGroupMembers(id string) []*Entity {
+	ug := self.FindUserGroup(id)
+	if ug == nil {
+		return nil
+	}
+	entities := make([]*Entity, 0, len(ug.Users))
+	for _, user_id := range ug.Users {
+		if entity := self.FindEntity(user_id); entity != nil {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+// ResolveSubteamMentions returns the members of each user group
+// mentioned in text (as <!subteam^ID>), by group ID.
+func (self *StateManager) ResolveSubteamMentions(text string) map[string][]*Entity {
+	resolved := make(map[string][]*Entity)
+	for _, id := range ParseSubteamMentions(text) {
+		resolved[id] = self.UserGroupMembers(id)
+	}
+	return resolved
 }
 
 func (self *StateManager) FindEntity(id string) *Entity {
@@ -254,16 +572,7 @@ func (self *StateManager) AddHooks(ctx context.Context) error {
 
 	rtm.addHook("user_change", func(ctx context.Context, _msg RTMMessage) {
 		msg := _msg.(*RTMUserChangedMessage)
-
-		// Name might have changed, so find by ID first.
-		entity := self.FindEntity(msg.User.ID)
-
-		if entity != nil && entity.Name != msg.User.Name {
-			// Handle name change.
-			delete(self.EntitiesByName, entity.Name)
-			self.EntitiesByName[msg.User.Name] = entity
-		}
-		self.addEntityFromUser(msg.User)
+		self.UpdateUser(msg.User)
 	})
 
 	rtm.addHook("channel_created", func(ctx context.Context, _msg RTMMessage) {
@@ -288,6 +597,74 @@ func (self *StateManager) AddHooks(ctx context.Context) error {
 		self.addPlaceFromGroup(msg.Group)
 	})
 
+	rtm.addHook("subteam_created", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMSubteamMessage)
+		self.addUserGroup(msg.Subteam)
+	})
+
+	rtm.addHook("subteam_updated", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMSubteamMessage)
+		self.addUserGroup(msg.Subteam)
+	})
+
+	rtm.addHook("subteam_members_changed", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMSubteamMembersChangedMessage)
+		ug := self.FindUserGroup(msg.SubteamID)
+		if ug == nil {
+			return
+		}
+		// Removed users and ones we've already seen
+		skip := make(map[string]bool)
+		for _, user_id := range msg.RemovedUsers {
+			skip[user_id] = true
+		}
+		users := make([]string, 0, len(ug.Users)+len(msg.AddedUsers))
+		for _, user_ids := range [][]string{ug.Users, msg.AddedUsers} {
+			for _, user_id := range user_ids {
+				if !skip[user_id] {
+					skip[user_id] = true
+					users = append(users, user_id)
+				}
+			}
+		}
+		ug.Users = users
+	})
+
+	rtm.addHook("subteam_self_added", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMSubteamSelfMessage)
+		self.setSelfInUserGroup(msg.SubteamID, true)
+	})
+
+	rtm.addHook("subteam_self_removed", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMSubteamSelfMessage)
+		self.setSelfInUserGroup(msg.SubteamID, false)
+	})
+
+	rtm.addHook("emoji_changed", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMEmojiChangedMessage)
+		// Don't start a partial catalog
+		if self.Emoji != nil {
+			self.Emoji.update(msg)
+		}
+	})
+
+	rtm.addHook("pin_added", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMPinMessage)
+		if place := self.FindPlace(msg.ChannelID); place != nil && msg.Item != nil {
+			place.addPin(msg.Item)
+		}
+	})
+
+	rtm.addHook("pin_removed", func(ctx context.Context, _msg RTMMessage) {
+		msg := _msg.(*RTMPinMessage)
+		if place := self.FindPlace(msg.ChannelID); place != nil && msg.Item != nil {
+			place.delPin(msg.Item.Ref())
+			if place.Group != nil {
+				place.Group.HasPins = msg.HasPins
+			}
+		}
+	})
+
 	return nil
 }
 
@@ -304,6 +681,9 @@ func (self *StateManager) RTMStart(ctx context.Context, resp *RTMStartResponse)
 	self.EntitiesByName = make(map[string]*Entity)
 	self.PlacesByID = make(map[string]*Place)
 	self.PlacesByName = make(map[string]*Place)
+	self.UserGroupsByID = make(map[string]*UserGroup)
+	self.UserGroupsByHandle = make(map[string]*UserGroup)
+	self.SelfUserGroups = make(map[string]bool)
 
 	self.addEntityFromSelf(self.Self)
 
@@ -324,6 +704,108 @@ func (self *StateManager) RTMStart(ctx context.Context, resp *RTMStartResponse)
 	for _, group := range self.Groups {
 		self.addPlaceFromGroup(group)
 	}
+	if resp.Subteams != nil {
+		for _, ug := range resp.Subteams.All {
+			self.addUserGroup(ug)
+		}
+		for _, id := range resp.Subteams.Self {
+			self.SelfUserGroups[id] = true
+		}
+	}
 
 	return nil
 }
+
+// AddConversations adds places for conversations fetched with the
+// conversations.* methods, e.g. ConversationsList. Call it after RTMStart
+// so that IMs can be named after their users.
+func (self *StateManager) AddConversations(convs []*Conversation) {
+	for _, conv := range convs {
+		self.addPlaceFromConversation(conv)
+	}
+}
+
+// Updates the Place, and the Channel, Group or IM it wraps, in place if
+// we already know about the conversation, so that anything holding on
+// to them (entities, the Channels/Groups/IMs slices) stays current.
+func (self *StateManager) addPlaceFromConversation(conv *Conversation) *Place {
+	var fresh *Place
+
+	switch {
+	case conv.IsIM:
+		fresh = self.newPlaceFromIM(conv.ToIM())
+	case conv.IsPublicChannel():
+		fresh = newPlaceFromChannel(conv.ToChannel())
+	default:
+		fresh = newPlaceFromGroup(conv.ToGroup())
+	}
+
+	place := self.FindPlace(conv.ID)
+	if place == nil {
+		self.addConversationObj(fresh)
+		return self.addPlace(fresh)
+	}
+
+	switch {
+	case place.Channel != nil && fresh.Channel != nil:
+		*place.Channel = *fresh.Channel
+		fresh.Channel = place.Channel
+	case place.Group != nil && fresh.Group != nil:
+		// Conversations don't say whether anything is pinned.
+		fresh.Group.HasPins = place.Group.HasPins
+		*place.Group = *fresh.Group
+		fresh.Group = place.Group
+	case place.IM != nil && fresh.IM != nil:
+		*place.IM = *fresh.IM
+		fresh.IM = place.IM
+	default:
+		// It changed type, e.g. a channel was made private.
+		self.delConversationObj(place)
+		self.addConversationObj(fresh)
+	}
+
+	delete(self.PlacesByName, place.Name)
+	fresh.Pins = place.Pins
+	*place = *fresh
+
+	return self.addPlace(place)
+}
+
+// Adds the Channel, Group or IM in place to the matching slice.
+func (self *StateManager) addConversationObj(place *Place) {
+	switch {
+	case place.Channel != nil:
+		self.Channels = append(self.Channels, place.Channel)
+	case place.Group != nil:
+		self.Groups = append(self.Groups, place.Group)
+	case place.IM != nil:
+		self.IMs = append(self.IMs, place.IM)
+	}
+}
+
+// Removes the Channel, Group or IM in place from the matching slice.
+func (self *StateManager) delConversationObj(place *Place) {
+	switch {
+	case place.Channel != nil:
+		for i, channel := range self.Channels {
+			if channel == place.Channel {
+				self.Channels = append(self.Channels[:i], self.Channels[i+1:]...)
+				break
+			}
+		}
+	case place.Group != nil:
+		for i, group := range self.Groups {
+			if group == place.Group {
+				self.Groups = append(self.Groups[:i], self.Groups[i+1:]...)
+				break
+			}
+		}
+	case place.IM != nil:
+		for i, im := range self.IMs {
+			if im == place.IM {
+				self.IMs = append(self.IMs[:i], self.IMs[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package slopher
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestAddConversationsUpdatesInPlace(t *testing.T) {
+	sm := GetDefaultStateManager()
+	err := sm.RTMStart(context.Background(), &RTMStartResponse{
+		Self:  &Self{ID: "U0", Name: "bot"},
+		Users: []*User{{ID: "U1", Name: "alice"}},
+	})
+	if err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+
+	sm.AddConversations([]*Conversation{
+		{ID: "C1", Name: "general", IsChannel: true, Members: []string{"U1"}},
+	})
+	place := sm.FindPlace("C1")
+	if place == nil || len(sm.Channels) != 1 || place.Channel != sm.Channels[0] {
+		t.Fatalf("place = %+v, Channels = %v", place, sm.Channels)
+	}
+	channel := place.Channel
+	place.Pins = []*PinnedItem{{Type: "message", ChannelID: "C1"}}
+
+	sm.AddConversations([]*Conversation{
+		{ID: "C1", Name: "random", IsChannel: true, Members: []string{"U1"}},
+	})
+	if sm.FindPlace("C1") != place || place.Channel != channel {
+		t.Error("updating the conversation replaced its Place or Channel")
+	}
+	if len(sm.Channels) != 1 || channel.Name != "random" || place.Name != "#random" {
+		t.Errorf("Channels = %v, Name = %q", sm.Channels, place.Name)
+	}
+	if sm.PlacesByName["#general"] != nil || sm.PlacesByName["#random"] != place {
+		t.Error("PlacesByName wasn't updated for the rename")
+	}
+	if len(place.Pins) != 1 {
+		t.Error("Pins were lost")
+	}
+	if sm.FindEntity("U1").PlacesByID["C1"] != place {
+		t.Error("member entity doesn't have the Place")
+	}
+
+	// Made private: it moves from Channels to Groups.
+	sm.AddConversations([]*Conversation{
+		{ID: "C1", Name: "random", IsChannel: true, IsPrivate: true},
+	})
+	if sm.FindPlace("C1") != place || !place.IsGroup || place.IsChannel {
+		t.Errorf("place = %+v", place)
+	}
+	if len(sm.Channels) != 0 || len(sm.Groups) != 1 || sm.Groups[0] != place.Group {
+		t.Errorf("Channels = %v, Groups = %v", sm.Channels, sm.Groups)
+	}
+}
+
+func TestAddConversationsKeepsHasPins(t *testing.T) {
+	sm := GetDefaultStateManager()
+	err := sm.RTMStart(context.Background(), &RTMStartResponse{
+		Self:   &Self{ID: "U0", Name: "bot"},
+		Groups: []*Group{{ID: "G1", Name: "secret", IsGroup: true, HasPins: true}},
+	})
+	if err != nil {
+		t.Fatalf("RTMStart: %v", err)
+	}
+	place := sm.FindPlace("G1")
+
+	sm.AddConversations([]*Conversation{
+		{ID: "G1", Name: "secret-plans", IsChannel: true, IsPrivate: true},
+	})
+	if sm.FindPlace("G1") != place || place.Group.Name != "secret-plans" {
+		t.Fatalf("place = %+v", place)
+	}
+	if !place.Group.HasPins || !place.HasPins() {
+		t.Error("updating the conversation reset HasPins")
+	}
+}
+
+func TestUpdateUserWithoutRTMStart(t *testing.T) {
+	sm := GetDefaultStateManager()
+
+	entity := sm.UpdateUser(&User{ID: "U1", Name: "alice"})
+	if entity == nil || sm.FindEntity("U1") != entity || sm.FindEntityByName("alice") != entity {
+		t.Fatalf("UpdateUser = %+v", entity)
+	}
+
+	sm.AddConversations([]*Conversation{{ID: "D1", IsIM: true, UserID: "U1"}})
+	if place := sm.FindPlace("D1"); place == nil || place.Name != "alice" {
+		t.Errorf("FindPlace(D1) = %+v", place)
+	}
+
+	sm.UpdateUser(&User{ID: "U1", Name: "alicia"})
+	if sm.FindEntityByName("alice") != nil || sm.FindEntityByName("alicia") != entity {
+		t.Error("rename wasn't applied")
+	}
+	if sm.PlacesByName["alicia"] == nil {
+		t.Error("IM wasn't renamed")
+	}
+}
@@ -0,0 +1,73 @@
+package slopher_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/comstud/slopher/slacktest"
+	"golang.org/x/net/context"
+)
+
+func TestRTMProcessorAgainstFakeServer(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.AddUser(&slopher.User{ID: "U1", Name: "alice"})
+	srv.AddChannel(&slopher.Channel{
+		ID:        "C1",
+		Name:      "general",
+		IsChannel: true,
+		IsMember:  true,
+		Members:   []string{slacktest.DefaultSelfID, "U1"},
+	})
+
+	client := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+	state_mgr := slopher.GetDefaultStateManager()
+
+	ctx := client.NewContext(context.Background())
+	ctx = slopher.NewContextForStateManager(ctx, state_mgr)
+
+	rtm, err := slopher.NewRTMProcessor(ctx)
+	if err != nil {
+		t.Fatalf("NewRTMProcessor: %v", err)
+	}
+
+	if place := state_mgr.FindPlace("C1"); place == nil || place.Name != "#general" {
+		t.Errorf("FindPlace(C1) = %+v", place)
+	}
+	if entity := state_mgr.FindEntity("U1"); entity == nil || entity.Name != "alice" {
+		t.Errorf("FindEntity(U1) = %+v", entity)
+	}
+
+	rtm.OnChannelMessage(func(ctx context.Context, _msg slopher.RTMMessage) {
+		msg := _msg.(*slopher.RTMChannelMessage)
+		if msg.Text != "ping" {
+			return
+		}
+		place := state_mgr.FindPlace(msg.ChannelID)
+		if err := place.SendMessageString(ctx, "pong"); err != nil {
+			t.Errorf("SendMessageString: %v", err)
+		}
+	})
+
+	ctx = rtm.NewContext(ctx)
+	if err := rtm.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer rtm.Stop(ctx, false)
+
+	if err := srv.WaitForRTMConnection(5 * time.Second); err != nil {
+		t.Fatalf("WaitForRTMConnection: %v", err)
+	}
+	if err := srv.SendMessage("C1", "U1", "ping"); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	msgs, err := srv.WaitForSentMessages(1, 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForSentMessages: %v", err)
+	}
+	if msgs[0].ChannelID != "C1" || msgs[0].Text != "pong" {
+		t.Errorf("sent %+v", msgs[0].BaseMessage)
+	}
+}
@@ -0,0 +1,1720 @@
+// Package slacktest provides an in-process fake Slack server for testing
+// bots built with slopher. It implements the Web API methods slopher
+// uses plus an RTM websocket endpoint:
+//
+//	srv := slacktest.NewServer()
+//	defer srv.Close()
+//	srv.AddChannel(&slopher.Channel{ID: "C1", Name: "general"})
+//
+//	cli := slopher.NewClient(srv.APIURL(), "xoxb-test", nil)
+//	... start an RTMProcessor with cli ...
+//
+//	srv.WaitForRTMConnection(time.Second)
+//	srv.SendMessage("C1", "U1", "hello bot")
+//	msgs, err := srv.WaitForSentMessages(1, time.Second)
+//
+// Uploaded files are kept, and served from their private URLs.
+package slacktest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comstud/slopher"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DefaultTeamID = "T00000001"
+	DefaultSelfID = "U00000001"
+)
+
+// Request is an API call received by the Server. JSON bodies are
+// flattened into Args the same way form fields are.
+type Request struct {
+	Method string
+	Token  string
+	Args   slopher.APIArgs
+	// Uploaded file contents, if any
+	File []byte
+}
+
+// HandlerFunc answers an API method. The result is sent as JSON; a nil
+// result is sent as {"ok": true}.
+type HandlerFunc func(req *Request) interface{}
+
+type rtmConn struct {
+	ws    *websocket.Conn
+	mutex sync.Mutex
+}
+
+func (self *rtmConn) writeJSON(v interface{}) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.ws.WriteJSON(v)
+}
+
+type Server struct {
+	Self *slopher.Self
+	Team *slopher.Team
+
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mutex    sync.Mutex
+	changed  *sync.Cond
+	handlers map[string]HandlerFunc
+	users    []*slopher.User
+	bots     []*slopher.Bot
+	channels []*slopher.Channel
+	groups   []*slopher.Group
+	ims      []*slopher.IM
+	files    map[string]*slopher.SharedFile
+	contents map[string][]byte
+	conns    []*rtmConn
+	requests []*Request
+	sent     []*slopher.Message
+	posted   []*slopher.Message
+	sched    []*slopher.ScheduledMessage
+	pins     map[string][]*slopher.PinnedItem
+	ugroups  []*slopher.UserGroup
+	emoji    map[string]string
+	reminds  []*slopher.Reminder
+	presence map[string]string
+	seq      int64
+}
+
+// NewServer starts a Server. Call Close when done with it.
+func NewServer() *Server {
+	self := &Server{
+		Self: &slopher.Self{ID: DefaultSelfID, Name: "slacktest-bot"},
+		Team: &slopher.Team{ID: DefaultTeamID, Name: "slacktest",
+			Domain: "slacktest"},
+		handlers: make(map[string]HandlerFunc),
+		files:    make(map[string]*slopher.SharedFile),
+		contents: make(map[string][]byte),
+		pins:     make(map[string][]*slopher.PinnedItem),
+		emoji:    make(map[string]string),
+		presence: make(map[string]string),
+	}
+	self.changed = sync.NewCond(&self.mutex)
+
+	self.handlers["rtm.start"] = self.rtmStart
+	self.handlers["auth.test"] = self.authTest
+	self.handlers["chat.postMessage"] = self.chatPostMessage
+	self.handlers["chat.delete"] = self.chatDelete
+	self.handlers["chat.update"] = self.chatUpdate
+	self.handlers["chat.postEphemeral"] = self.chatPostEphemeral
+	self.handlers["chat.getPermalink"] = self.chatGetPermalink
+	self.handlers["chat.meMessage"] = self.chatMeMessage
+	self.handlers["chat.scheduleMessage"] = self.chatScheduleMessage
+	self.handlers["chat.scheduledMessages.list"] = self.chatScheduledMessagesList
+	self.handlers["chat.deleteScheduledMessage"] = self.chatDeleteScheduledMessage
+	self.handlers["reactions.add"] = self.reactionsAdd
+	self.handlers["reactions.remove"] = self.reactionsRemove
+	self.handlers["reactions.get"] = self.reactionsGet
+	self.handlers["users.info"] = self.usersInfo
+	self.handlers["users.list"] = self.usersList
+	self.handlers["users.lookupByEmail"] = self.usersLookupByEmail
+	self.handlers["users.getPresence"] = self.usersGetPresence
+	self.handlers["users.setPresence"] = self.usersSetPresence
+	self.handlers["users.conversations"] = self.usersConversations
+	self.handlers["users.profile.get"] = self.usersProfileGet
+	self.handlers["users.profile.set"] = self.usersProfileSet
+	self.handlers["usergroups.list"] = self.usergroupsList
+	self.handlers["usergroups.create"] = self.usergroupsCreate
+	self.handlers["usergroups.update"] = self.usergroupsUpdate
+	self.handlers["usergroups.enable"] = self.usergroupsEnable
+	self.handlers["usergroups.disable"] = self.usergroupsDisable
+	self.handlers["usergroups.users.list"] = self.usergroupsUsersList
+	self.handlers["usergroups.users.update"] = self.usergroupsUsersUpdate
+	self.handlers["search.messages"] = self.searchMessages
+	self.handlers["emoji.list"] = self.emojiList
+	self.handlers["reminders.add"] = self.remindersAdd
+	self.handlers["reminders.info"] = self.remindersInfo
+	self.handlers["reminders.list"] = self.remindersList
+	self.handlers["reminders.complete"] = self.remindersComplete
+	self.handlers["reminders.delete"] = self.remindersDelete
+	self.handlers["team.info"] = self.teamInfo
+	self.handlers["pins.add"] = self.pinsAdd
+	self.handlers["pins.remove"] = self.pinsRemove
+	self.handlers["pins.list"] = self.pinsList
+	self.handlers["conversations.leave"] = self.conversationsLeave
+	self.handlers["conversations.join"] = self.conversationsJoin
+	self.handlers["conversations.info"] = self.conversationsInfo
+	self.handlers["conversations.list"] = self.conversationsList
+	self.handlers["conversations.members"] = self.conversationsMembers
+	self.handlers["files.upload"] = self.filesUpload
+	self.handlers["files.getUploadURLExternal"] = self.filesGetUploadURLExternal
+	self.handlers["files.completeUploadExternal"] = self.filesCompleteUploadExternal
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", self.serveAPI)
+	mux.HandleFunc("/ws", self.serveRTM)
+	mux.HandleFunc("/upload/", self.serveUpload)
+	mux.HandleFunc("/files/", self.serveFile)
+	self.srv = httptest.NewServer(mux)
+
+	return self
+}
+
+func (self *Server) Close() {
+	self.mutex.Lock()
+	conns := self.conns
+	self.conns = nil
+	self.mutex.Unlock()
+
+	for _, conn := range conns {
+		conn.ws.Close()
+	}
+	self.srv.Close()
+}
+
+// APIURL is the uri to pass to slopher.NewClient.
+func (self *Server) APIURL() string {
+	return self.srv.URL + "/api"
+}
+
+func (self *Server) wsURL() string {
+	return "ws" + strings.TrimPrefix(self.srv.URL, "http") + "/ws"
+}
+
+// Handle replaces (or adds) the handler for an API method.
+func (self *Server) Handle(method string, fn HandlerFunc) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.handlers[method] = fn
+}
+
+func (self *Server) AddUser(user *slopher.User) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.users = append(self.users, user)
+}
+
+func (self *Server) AddBot(bot *slopher.Bot) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.bots = append(self.bots, bot)
+}
+
+func (self *Server) AddChannel(channel *slopher.Channel) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	channel.IsChannel = true
+	self.channels = append(self.channels, channel)
+}
+
+func (self *Server) AddGroup(group *slopher.Group) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	group.IsGroup = true
+	self.groups = append(self.groups, group)
+}
+
+func (self *Server) AddIM(im *slopher.IM) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	im.IsIM = true
+	self.ims = append(self.ims, im)
+}
+
+func (self *Server) AddUserGroup(ug *slopher.UserGroup) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	ug.IsUserGroup = true
+	self.ugroups = append(self.ugroups, ug)
+}
+
+// AddEmoji adds a custom emoji. value is an image URL or
+// "alias:<name>".
+func (self *Server) AddEmoji(name, value string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.emoji[name] = value
+}
+
+// AddFile adds a file with the given content, which is served (with
+// the token) from its private URLs. An ID is assigned if it has none.
+func (self *Server) AddFile(file *slopher.SharedFile, content []byte) {
+	if file.ID == "" {
+		file.ID = self.nextID("F")
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.storeFile(file, content)
+}
+
+// FileContent returns what was uploaded for (or added as) a file.
+func (self *Server) FileContent(id string) ([]byte, bool) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	content, ok := self.contents[id]
+	return content, ok
+}
+
+// Must be called with the mutex held.
+func (self *Server) storeFile(file *slopher.SharedFile, content []byte) {
+	file.URLPrivate = self.srv.URL + "/files/" + file.ID
+	file.URLPrivateDownload = file.URLPrivate + "?download=1"
+	file.Size = int64(len(content))
+	self.files[file.ID] = file
+	self.contents[file.ID] = content
+}
+
+// Requests returns every API call received so far.
+func (self *Server) Requests() []*Request {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return append([]*Request(nil), self.requests...)
+}
+
+// SentMessages returns the messages the bot sent over RTM (e.g. with
+// SendWSMessage).
+func (self *Server) SentMessages() []*slopher.Message {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return append([]*slopher.Message(nil), self.sent...)
+}
+
+// PostedMessages returns the messages the bot posted with
+// chat.postMessage.
+func (self *Server) PostedMessages() []*slopher.Message {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return append([]*slopher.Message(nil), self.posted...)
+}
+
+// ScheduledMessages returns the messages scheduled with
+// chat.scheduleMessage and not deleted. They're never posted.
+func (self *Server) ScheduledMessages() []*slopher.ScheduledMessage {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return append([]*slopher.ScheduledMessage(nil), self.sched...)
+}
+
+// WaitForSentMessages waits until at least n messages were sent over RTM
+// and returns them.
+func (self *Server) WaitForSentMessages(n int, timeout time.Duration) ([]*slopher.Message, error) {
+	err := self.waitFor(timeout, func() bool { return len(self.sent) >= n })
+	return self.SentMessages(), err
+}
+
+// WaitForPostedMessages waits until at least n messages were posted with
+// chat.postMessage and returns them.
+func (self *Server) WaitForPostedMessages(n int, timeout time.Duration) ([]*slopher.Message, error) {
+	err := self.waitFor(timeout, func() bool { return len(self.posted) >= n })
+	return self.PostedMessages(), err
+}
+
+// WaitForRTMConnection waits until a client connects to the websocket.
+func (self *Server) WaitForRTMConnection(timeout time.Duration) error {
+	return self.waitFor(timeout, func() bool { return len(self.conns) > 0 })
+}
+
+// Waits for cond (called with the mutex held) to become true.
+func (self *Server) waitFor(timeout time.Duration, cond func() bool) error {
+	timer := time.AfterFunc(timeout, func() {
+		self.mutex.Lock()
+		self.changed.Broadcast()
+		self.mutex.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for !cond() {
+		if !time.Now().Before(deadline) {
+			return errors.New("slacktest: timed out")
+		}
+		self.changed.Wait()
+	}
+	return nil
+}
+
+// SendEvent sends an RTM event (anything that marshals to a JSON object
+// with a "type") to every connected client.
+func (self *Server) SendEvent(event interface{}) error {
+	self.mutex.Lock()
+	conns := append([]*rtmConn(nil), self.conns...)
+	self.mutex.Unlock()
+
+	if len(conns) == 0 {
+		return errors.New("slacktest: no RTM connections")
+	}
+
+	for _, conn := range conns {
+		if err := conn.writeJSON(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendMessage sends a "message" event from user_id to channel_id.
+func (self *Server) SendMessage(channel_id, user_id, text string) error {
+	return self.SendEvent(map[string]interface{}{
+		"type":    "message",
+		"channel": channel_id,
+		"user":    user_id,
+		"text":    text,
+		"ts":      self.nextTS(),
+	})
+}
+
+func (self *Server) nextTS() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.seq++
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), self.seq)
+}
+
+func (self *Server) nextID(prefix string) string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.seq++
+	return fmt.Sprintf("%s%08d", prefix, self.seq)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func okResponse(fields map[string]interface{}) map[string]interface{} {
+	resp := map[string]interface{}{"ok": true}
+	for k, v := range fields {
+		resp[k] = v
+	}
+	return resp
+}
+
+// ErrorResponse returns an {"ok": false} response for a HandlerFunc.
+func ErrorResponse(code string) interface{} {
+	return map[string]interface{}{"ok": false, "error": code}
+}
+
+func (self *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse("invalid_form_data"))
+		return
+	}
+
+	self.mutex.Lock()
+	self.requests = append(self.requests, req)
+	handler, ok := self.handlers[req.Method]
+	self.changed.Broadcast()
+	self.mutex.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusOK, ErrorResponse("unknown_method"))
+		return
+	}
+
+	if req.Token == "" {
+		writeJSON(w, http.StatusOK, ErrorResponse("not_authed"))
+		return
+	}
+
+	resp := handler(req)
+	if resp == nil {
+		resp = okResponse(nil)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseRequest(r *http.Request) (*Request, error) {
+	req := &Request{
+		Method: strings.TrimPrefix(r.URL.Path, "/api/"),
+		Args:   slopher.APIArgs{},
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		req.Token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	content_type, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch content_type {
+	case "application/json":
+		var fields map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			return nil, err
+		}
+		for k, raw := range fields {
+			var str string
+			if err := json.Unmarshal(raw, &str); err == nil {
+				req.Args[k] = str
+			} else {
+				req.Args[k] = string(raw)
+			}
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			req.Args[k] = v[0]
+		}
+		if fhs := r.MultipartForm.File["file"]; len(fhs) > 0 {
+			f, err := fhs[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			if req.File, err = ioutil.ReadAll(f); err != nil {
+				return nil, err
+			}
+			req.Args["filename"] = fhs[0].Filename
+		}
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for k, v := range r.Form {
+			req.Args[k] = v[0]
+		}
+	}
+
+	if req.Token == "" {
+		req.Token = req.Args["token"]
+	}
+
+	return req, nil
+}
+
+func (self *Server) serveRTM(w http.ResponseWriter, r *http.Request) {
+	ws, err := self.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	conn := &rtmConn{ws: ws}
+	conn.writeJSON(map[string]interface{}{"type": "hello"})
+
+	self.mutex.Lock()
+	self.conns = append(self.conns, conn)
+	self.changed.Broadcast()
+	self.mutex.Unlock()
+
+	defer func() {
+		self.mutex.Lock()
+		for i, c := range self.conns {
+			if c == conn {
+				self.conns = append(self.conns[:i], self.conns[i+1:]...)
+				break
+			}
+		}
+		self.changed.Broadcast()
+		self.mutex.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		msg := &slopher.Message{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			continue
+		}
+
+		ts := self.nextTS()
+		msg.TS = ts
+
+		self.mutex.Lock()
+		self.sent = append(self.sent, msg)
+		self.changed.Broadcast()
+		self.mutex.Unlock()
+
+		if msg.Id != nil {
+			conn.writeJSON(map[string]interface{}{
+				"ok":       true,
+				"reply_to": *msg.Id,
+				"ts":       ts,
+				"text":     msg.Text,
+			})
+		}
+	}
+}
+
+func (self *Server) serveUpload(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/upload/")
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	file, ok := self.files[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	self.storeFile(file, data)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Like Slack, answers with a login page rather than an error if there's
+// no token.
+func (self *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/files/")
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Sign in to slacktest</body></html>"))
+		return
+	}
+
+	self.mutex.Lock()
+	file, ok := self.files[id]
+	content := self.contents[id]
+	self.mutex.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	content_type := file.MimeType
+	if content_type == "" {
+		content_type = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", content_type)
+	w.Write(content)
+}
+
+/*
+** Built-in API method handlers
+ */
+
+func (self *Server) rtmStart(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{
+		"url":      self.wsURL(),
+		"self":     self.Self,
+		"team":     self.Team,
+		"users":    self.users,
+		"bots":     self.bots,
+		"channels": self.channels,
+		"groups":   self.groups,
+		"ims":      self.ims,
+		"subteams": map[string]interface{}{
+			"self": self.selfUserGroups(),
+			"all":  self.ugroups,
+		},
+	})
+}
+
+func (self *Server) teamInfo(req *Request) interface{} {
+	if team := req.Args["team"]; team != "" && team != self.Team.ID {
+		return ErrorResponse("team_not_found")
+	}
+
+	return okResponse(map[string]interface{}{"team": self.Team})
+}
+
+func (self *Server) authTest(req *Request) interface{} {
+	return okResponse(map[string]interface{}{
+		"url":     self.srv.URL + "/",
+		"team":    self.Team.Name,
+		"user":    self.Self.Name,
+		"team_id": self.Team.ID,
+		"user_id": self.Self.ID,
+	})
+}
+
+func (self *Server) chatPostMessage(req *Request) interface{} {
+	channel := req.Args["channel"]
+	if channel == "" {
+		return ErrorResponse("channel_not_found")
+	}
+
+	msg := &slopher.Message{
+		BaseMessage: slopher.BaseMessage{
+			Type:      "message",
+			UserID:    self.Self.ID,
+			ChannelID: channel,
+			Text:      req.Args["text"],
+			TS:        self.nextTS(),
+			ThreadTS:  req.Args["thread_ts"],
+		},
+	}
+	if attachments := req.Args["attachments"]; attachments != "" {
+		json.Unmarshal([]byte(attachments), &msg.Attachments)
+	}
+
+	self.mutex.Lock()
+	self.posted = append(self.posted, msg)
+	self.changed.Broadcast()
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{
+		"channel": channel,
+		"ts":      msg.TS,
+		"message": msg,
+	})
+}
+
+func (self *Server) chatDelete(req *Request) interface{} {
+	return okResponse(map[string]interface{}{
+		"channel": req.Args["channel"],
+		"ts":      req.Args["ts"],
+	})
+}
+
+// Updates the text of a message we've posted.
+func (self *Server) chatUpdate(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, msg := range self.posted {
+		if msg.ChannelID == req.Args["channel"] && msg.TS == req.Args["ts"] {
+			msg.Text = req.Args["text"]
+			return okResponse(map[string]interface{}{
+				"channel": msg.ChannelID,
+				"ts":      msg.TS,
+				"text":    msg.Text,
+				"message": msg,
+			})
+		}
+	}
+
+	return ErrorResponse("message_not_found")
+}
+
+func (self *Server) chatPostEphemeral(req *Request) interface{} {
+	if req.Args["channel"] == "" {
+		return ErrorResponse("channel_not_found")
+	}
+	if req.Args["user"] == "" {
+		return ErrorResponse("user_not_in_channel")
+	}
+
+	return okResponse(map[string]interface{}{"message_ts": self.nextTS()})
+}
+
+func (self *Server) chatGetPermalink(req *Request) interface{} {
+	channel := req.Args["channel"]
+	ts := req.Args["message_ts"]
+
+	return okResponse(map[string]interface{}{
+		"channel": channel,
+		"permalink": fmt.Sprintf("https://%s.slack.com/archives/%s/p%s",
+			self.Team.Domain, channel, strings.Replace(ts, ".", "", 1)),
+	})
+}
+
+// Recorded with the posted messages.
+func (self *Server) chatMeMessage(req *Request) interface{} {
+	channel := req.Args["channel"]
+	if channel == "" {
+		return ErrorResponse("channel_not_found")
+	}
+
+	msg := &slopher.Message{
+		BaseMessage: slopher.BaseMessage{
+			Type:      "message",
+			SubType:   "me_message",
+			UserID:    self.Self.ID,
+			ChannelID: channel,
+			Text:      req.Args["text"],
+			TS:        self.nextTS(),
+		},
+	}
+
+	self.mutex.Lock()
+	self.posted = append(self.posted, msg)
+	self.changed.Broadcast()
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{
+		"channel": channel,
+		"ts":      msg.TS,
+	})
+}
+
+func (self *Server) chatScheduleMessage(req *Request) interface{} {
+	channel := req.Args["channel"]
+	if channel == "" {
+		return ErrorResponse("channel_not_found")
+	}
+	post_at, err := strconv.ParseInt(req.Args["post_at"], 10, 64)
+	if err != nil || post_at <= time.Now().Unix() {
+		return ErrorResponse("time_in_past")
+	}
+
+	sched := &slopher.ScheduledMessage{
+		ID:          self.nextID("Q"),
+		ChannelID:   channel,
+		PostAt:      slopher.EpochTime(time.Unix(post_at, 0)),
+		DateCreated: slopher.EpochTime(time.Now()),
+		Text:        req.Args["text"],
+	}
+
+	self.mutex.Lock()
+	self.sched = append(self.sched, sched)
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{
+		"channel":              channel,
+		"scheduled_message_id": sched.ID,
+		"post_at":              post_at,
+		"message": &slopher.Message{
+			BaseMessage: slopher.BaseMessage{
+				Type:   "message",
+				UserID: self.Self.ID,
+				Text:   sched.Text,
+			},
+		},
+	})
+}
+
+// Filters by channel but returns everything in one page.
+func (self *Server) chatScheduledMessagesList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	scheds := make([]*slopher.ScheduledMessage, 0)
+	for _, sched := range self.sched {
+		if channel := req.Args["channel"]; channel == "" || channel == sched.ChannelID {
+			scheds = append(scheds, sched)
+		}
+	}
+
+	return okResponse(map[string]interface{}{"scheduled_messages": scheds})
+}
+
+func (self *Server) chatDeleteScheduledMessage(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i, sched := range self.sched {
+		if sched.ID == req.Args["scheduled_message_id"] &&
+			sched.ChannelID == req.Args["channel"] {
+			self.sched = append(self.sched[:i], self.sched[i+1:]...)
+			return okResponse(nil)
+		}
+	}
+
+	return ErrorResponse("invalid_scheduled_message_id")
+}
+
+// Reactions are only tracked on posted messages. Must be called with the
+// mutex held.
+func (self *Server) findPosted(channel_id, ts string) *slopher.Message {
+	for _, msg := range self.posted {
+		if msg.ChannelID == channel_id && msg.TS == ts {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (self *Server) reactionsAdd(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	msg := self.findPosted(req.Args["channel"], req.Args["timestamp"])
+	if msg == nil {
+		return ErrorResponse("message_not_found")
+	}
+
+	name := req.Args["name"]
+	for i := range msg.Reactions {
+		reaction := &msg.Reactions[i]
+		if reaction.Name != name {
+			continue
+		}
+		for _, user := range reaction.Users {
+			if user == self.Self.ID {
+				return ErrorResponse("already_reacted")
+			}
+		}
+		reaction.Users = append(reaction.Users, self.Self.ID)
+		reaction.Count++
+		return okResponse(nil)
+	}
+	msg.Reactions = append(msg.Reactions, slopher.Reaction{
+		Name:  name,
+		Count: 1,
+		Users: []string{self.Self.ID},
+	})
+
+	return okResponse(nil)
+}
+
+func (self *Server) reactionsRemove(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	msg := self.findPosted(req.Args["channel"], req.Args["timestamp"])
+	if msg == nil {
+		return ErrorResponse("message_not_found")
+	}
+
+	for i, reaction := range msg.Reactions {
+		if reaction.Name != req.Args["name"] {
+			continue
+		}
+		for j, user := range reaction.Users {
+			if user != self.Self.ID {
+				continue
+			}
+			reaction.Users = append(reaction.Users[:j], reaction.Users[j+1:]...)
+			reaction.Count--
+			if reaction.Count == 0 {
+				msg.Reactions = append(msg.Reactions[:i], msg.Reactions[i+1:]...)
+			} else {
+				msg.Reactions[i] = reaction
+			}
+			return okResponse(nil)
+		}
+	}
+
+	return ErrorResponse("no_reaction")
+}
+
+func (self *Server) reactionsGet(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	msg := self.findPosted(req.Args["channel"], req.Args["timestamp"])
+	if msg == nil {
+		return ErrorResponse("message_not_found")
+	}
+
+	return okResponse(map[string]interface{}{
+		"type":    "message",
+		"channel": msg.ChannelID,
+		"message": msg,
+	})
+}
+
+// Only posted messages can be pinned. Connected RTM clients are sent
+// pin_added.
+func (self *Server) pinsAdd(req *Request) interface{} {
+	channel := req.Args["channel"]
+
+	self.mutex.Lock()
+	msg := self.findPosted(channel, req.Args["timestamp"])
+	if msg == nil {
+		self.mutex.Unlock()
+		return ErrorResponse("message_not_found")
+	}
+	for _, pin := range self.pins[channel] {
+		if pin.Message == msg {
+			self.mutex.Unlock()
+			return ErrorResponse("already_pinned")
+		}
+	}
+	item := &slopher.PinnedItem{
+		Type:      "message",
+		ChannelID: channel,
+		Created:   slopher.EpochTime(time.Now()),
+		CreatedBy: self.Self.ID,
+		Message:   msg,
+	}
+	self.pins[channel] = append(self.pins[channel], item)
+	self.mutex.Unlock()
+
+	self.SendEvent(map[string]interface{}{
+		"type":       "pin_added",
+		"user":       self.Self.ID,
+		"channel_id": channel,
+		"item":       item,
+		"event_ts":   self.nextTS(),
+	})
+
+	return okResponse(nil)
+}
+
+func (self *Server) pinsRemove(req *Request) interface{} {
+	channel := req.Args["channel"]
+
+	self.mutex.Lock()
+	var item *slopher.PinnedItem
+	pins := self.pins[channel]
+	for i, pin := range pins {
+		if pin.Message.TS == req.Args["timestamp"] {
+			item = pin
+			self.pins[channel] = append(pins[:i], pins[i+1:]...)
+			break
+		}
+	}
+	has_pins := len(self.pins[channel]) > 0
+	self.mutex.Unlock()
+
+	if item == nil {
+		return ErrorResponse("no_pin")
+	}
+
+	self.SendEvent(map[string]interface{}{
+		"type":       "pin_removed",
+		"user":       self.Self.ID,
+		"channel_id": channel,
+		"item":       item,
+		"has_pins":   has_pins,
+		"event_ts":   self.nextTS(),
+	})
+
+	return okResponse(nil)
+}
+
+func (self *Server) pinsList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	items := append([]*slopher.PinnedItem{}, self.pins[req.Args["channel"]]...)
+
+	return okResponse(map[string]interface{}{"items": items})
+}
+
+func (self *Server) usersInfo(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, user := range self.users {
+		if user.ID == req.Args["user"] {
+			return okResponse(map[string]interface{}{"user": user})
+		}
+	}
+
+	return ErrorResponse("user_not_found")
+}
+
+// Pages by "limit", with the index of the next user as the cursor.
+func (self *Server) usersList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	start, _ := strconv.Atoi(req.Args["cursor"])
+	if start < 0 || start > len(self.users) {
+		return ErrorResponse("invalid_cursor")
+	}
+	end := len(self.users)
+	if limit, _ := strconv.Atoi(req.Args["limit"]); limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	users := append([]*slopher.User{}, self.users[start:end]...)
+
+	var next_cursor string
+	if end < len(self.users) {
+		next_cursor = strconv.Itoa(end)
+	}
+
+	return okResponse(map[string]interface{}{
+		"members":           users,
+		"response_metadata": map[string]string{"next_cursor": next_cursor},
+	})
+}
+
+func (self *Server) usersLookupByEmail(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, user := range self.users {
+		if user.Profile != nil && user.Profile.Email == req.Args["email"] {
+			return okResponse(map[string]interface{}{"user": user})
+		}
+	}
+
+	return ErrorResponse("users_not_found")
+}
+
+// Everyone is active except the token's user after setting "away".
+func (self *Server) usersGetPresence(req *Request) interface{} {
+	user_id := req.Args["user"]
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if user_id == "" {
+		user_id = self.Self.ID
+	}
+	if user_id != self.Self.ID && self.findUser(user_id) == nil {
+		return ErrorResponse("user_not_found")
+	}
+	presence := self.presence[user_id]
+	if presence == "" {
+		presence = "active"
+	}
+
+	fields := map[string]interface{}{"presence": presence}
+	if user_id == self.Self.ID {
+		fields["online"] = presence == "active"
+		fields["manual_away"] = presence == "away"
+		fields["connection_count"] = len(self.conns)
+	}
+	return okResponse(fields)
+}
+
+func (self *Server) usersSetPresence(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	switch req.Args["presence"] {
+	case "away":
+		self.presence[self.Self.ID] = "away"
+	case "auto":
+		delete(self.presence, self.Self.ID)
+	default:
+		return ErrorResponse("invalid_presence")
+	}
+
+	return okResponse(nil)
+}
+
+// The conversations user (default the token's) is in, all in one page.
+func (self *Server) usersConversations(req *Request) interface{} {
+	types := map[string]bool{"public_channel": true}
+	if req.Args["types"] != "" {
+		types = make(map[string]bool)
+		for _, t := range strings.Split(req.Args["types"], ",") {
+			types[t] = true
+		}
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	user_id := req.Args["user"]
+	if user_id == "" {
+		user_id = self.Self.ID
+	}
+
+	convs := make([]*slopher.Conversation, 0)
+	for _, conv := range self.conversations() {
+		member := conv.UserID == user_id || (user_id == self.Self.ID && (conv.IsIM || conv.IsMember))
+		for _, member_id := range conv.Members {
+			member = member || member_id == user_id
+		}
+		if !member || (conv.IsArchived && req.Args["exclude_archived"] == "true") {
+			continue
+		}
+		switch {
+		case conv.IsIM && types["im"],
+			conv.IsPrivate && types["private_channel"],
+			conv.IsPublicChannel() && types["public_channel"]:
+			convs = append(convs, conv)
+		}
+	}
+
+	return okResponse(map[string]interface{}{"channels": convs})
+}
+
+// Must be called with the mutex held.
+func (self *Server) selfUserGroups() []string {
+	ids := make([]string, 0)
+	for _, ug := range self.ugroups {
+		for _, user_id := range ug.Users {
+			if user_id == self.Self.ID {
+				ids = append(ids, ug.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// Must be called with the mutex held.
+func (self *Server) findUserGroup(id string) *slopher.UserGroup {
+	for _, ug := range self.ugroups {
+		if ug.ID == id {
+			return ug
+		}
+	}
+	return nil
+}
+
+// Returns a copy of ug, which may be changed after the mutex is
+// released.
+func userGroupResponse(ug *slopher.UserGroup) interface{} {
+	ug_copy := *ug
+	ug_copy.Users = append([]string{}, ug.Users...)
+	ug_copy.UserCount = json.Number(strconv.Itoa(len(ug.Users)))
+	return okResponse(map[string]interface{}{"usergroup": &ug_copy})
+}
+
+func (self *Server) usergroupsList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ugroups := make([]*slopher.UserGroup, 0, len(self.ugroups))
+	for _, ug := range self.ugroups {
+		if ug.IsDisabled() && req.Args["include_disabled"] != "true" {
+			continue
+		}
+		ug_copy := *ug
+		ug_copy.UserCount = json.Number(strconv.Itoa(len(ug.Users)))
+		if req.Args["include_users"] == "true" {
+			ug_copy.Users = append([]string{}, ug.Users...)
+		} else {
+			ug_copy.Users = nil
+		}
+		ugroups = append(ugroups, &ug_copy)
+	}
+
+	return okResponse(map[string]interface{}{"usergroups": ugroups})
+}
+
+func (self *Server) usergroupsCreate(req *Request) interface{} {
+	name := req.Args["name"]
+	if name == "" {
+		return ErrorResponse("invalid_name")
+	}
+	handle := req.Args["handle"]
+	if handle == "" {
+		handle = strings.ToLower(strings.Replace(name, " ", "-", -1))
+	}
+
+	ug := &slopher.UserGroup{
+		ID:          self.nextID("S"),
+		TeamID:      self.Team.ID,
+		IsUserGroup: true,
+		Name:        name,
+		Handle:      handle,
+		Description: req.Args["description"],
+		CreatedBy:   self.Self.ID,
+		DateCreate:  slopher.EpochTime(time.Now()),
+		Users:       []string{},
+	}
+	if channels := req.Args["channels"]; channels != "" {
+		ug.Prefs = &slopher.UserGroupPrefs{Channels: strings.Split(channels, ",")}
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for _, other := range self.ugroups {
+		if other.Name == ug.Name {
+			return ErrorResponse("name_already_exists")
+		}
+		if other.Handle == ug.Handle {
+			return ErrorResponse("handle_already_exists")
+		}
+	}
+	self.ugroups = append(self.ugroups, ug)
+
+	return userGroupResponse(ug)
+}
+
+func (self *Server) usergroupsUpdate(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ug := self.findUserGroup(req.Args["usergroup"])
+	if ug == nil {
+		return ErrorResponse("no_such_subteam")
+	}
+	if name := req.Args["name"]; name != "" {
+		ug.Name = name
+	}
+	if handle := req.Args["handle"]; handle != "" {
+		ug.Handle = handle
+	}
+	if description := req.Args["description"]; description != "" {
+		ug.Description = description
+	}
+	if channels := req.Args["channels"]; channels != "" {
+		ug.Prefs = &slopher.UserGroupPrefs{Channels: strings.Split(channels, ",")}
+	}
+	ug.UpdatedBy = self.Self.ID
+	ug.DateUpdate = slopher.EpochTime(time.Now())
+
+	return userGroupResponse(ug)
+}
+
+func (self *Server) usergroupsEnable(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ug := self.findUserGroup(req.Args["usergroup"])
+	if ug == nil {
+		return ErrorResponse("no_such_subteam")
+	}
+	if !ug.IsDisabled() {
+		return ErrorResponse("already_enabled")
+	}
+	ug.DateDelete = slopher.EpochTime{}
+	ug.DeletedBy = ""
+
+	return userGroupResponse(ug)
+}
+
+func (self *Server) usergroupsDisable(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ug := self.findUserGroup(req.Args["usergroup"])
+	if ug == nil {
+		return ErrorResponse("no_such_subteam")
+	}
+	if ug.IsDisabled() {
+		return ErrorResponse("already_disabled")
+	}
+	ug.DateDelete = slopher.EpochTime(time.Now())
+	ug.DeletedBy = self.Self.ID
+
+	return userGroupResponse(ug)
+}
+
+func (self *Server) usergroupsUsersList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ug := self.findUserGroup(req.Args["usergroup"])
+	if ug == nil {
+		return ErrorResponse("no_such_subteam")
+	}
+	users := append([]string{}, ug.Users...)
+
+	return okResponse(map[string]interface{}{"users": users})
+}
+
+func (self *Server) usergroupsUsersUpdate(req *Request) interface{} {
+	if req.Args["users"] == "" {
+		return ErrorResponse("invalid_users")
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	ug := self.findUserGroup(req.Args["usergroup"])
+	if ug == nil {
+		return ErrorResponse("no_such_subteam")
+	}
+	ug.Users = strings.Split(req.Args["users"], ",")
+	ug.UpdatedBy = self.Self.ID
+	ug.DateUpdate = slopher.EpochTime(time.Now())
+
+	return userGroupResponse(ug)
+}
+
+// Finds posted messages containing the query, all in one page.
+func (self *Server) searchMessages(req *Request) interface{} {
+	query := req.Args["query"]
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	matches := make([]map[string]interface{}, 0)
+	for _, msg := range self.posted {
+		if !strings.Contains(msg.Text, query) {
+			continue
+		}
+		matches = append(matches, map[string]interface{}{
+			"type": "message",
+			"user": msg.UserID,
+			"ts":   msg.TS,
+			"text": msg.Text,
+			"channel": map[string]interface{}{
+				"id":         msg.ChannelID,
+				"is_channel": true,
+			},
+			"permalink": fmt.Sprintf("https://%s.slack.com/archives/%s/p%s",
+				self.Team.Domain, msg.ChannelID,
+				strings.Replace(msg.TS, ".", "", 1)),
+		})
+	}
+
+	return okResponse(map[string]interface{}{
+		"query": query,
+		"messages": map[string]interface{}{
+			"total":   len(matches),
+			"matches": matches,
+			"paging": map[string]interface{}{
+				"count": len(matches),
+				"total": len(matches),
+				"page":  1,
+				"pages": 1,
+			},
+		},
+	})
+}
+
+func (self *Server) emojiList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	emoji := make(map[string]string)
+	for name, value := range self.emoji {
+		emoji[name] = value
+	}
+
+	return okResponse(map[string]interface{}{"emoji": emoji})
+}
+
+// Only takes Unix times, not natural language. Reminders never fire.
+func (self *Server) remindersAdd(req *Request) interface{} {
+	at, err := strconv.ParseInt(req.Args["time"], 10, 64)
+	if err != nil {
+		return ErrorResponse("cannot_parse")
+	}
+	if req.Args["text"] == "" {
+		return ErrorResponse("no_text")
+	}
+
+	// Like Slack, there's no "channel": reminders are only for users,
+	// defaulting to the token's.
+	reminder := &slopher.Reminder{
+		ID:      self.nextID("Rm"),
+		Creator: self.Self.ID,
+		UserID:  req.Args["user"],
+		Text:    req.Args["text"],
+		Time:    slopher.EpochTime(time.Unix(at, 0)),
+	}
+	if reminder.UserID == "" {
+		reminder.UserID = self.Self.ID
+	}
+
+	self.mutex.Lock()
+	self.reminds = append(self.reminds, reminder)
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{"reminder": reminder})
+}
+
+// Must be called with the mutex held.
+func (self *Server) findReminder(id string) (int, *slopher.Reminder) {
+	for i, reminder := range self.reminds {
+		if reminder.ID == id {
+			return i, reminder
+		}
+	}
+	return -1, nil
+}
+
+func (self *Server) remindersInfo(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	_, reminder := self.findReminder(req.Args["reminder"])
+	if reminder == nil {
+		return ErrorResponse("not_found")
+	}
+
+	return okResponse(map[string]interface{}{"reminder": reminder})
+}
+
+func (self *Server) remindersList(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	reminders := append([]*slopher.Reminder{}, self.reminds...)
+
+	return okResponse(map[string]interface{}{"reminders": reminders})
+}
+
+func (self *Server) remindersComplete(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	_, reminder := self.findReminder(req.Args["reminder"])
+	if reminder == nil {
+		return ErrorResponse("not_found")
+	}
+	if reminder.IsComplete() {
+		return ErrorResponse("already_complete")
+	}
+	reminder.CompleteTS = slopher.EpochTime(time.Now())
+
+	return okResponse(nil)
+}
+
+func (self *Server) remindersDelete(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	i, reminder := self.findReminder(req.Args["reminder"])
+	if reminder == nil {
+		return ErrorResponse("not_found")
+	}
+	self.reminds = append(self.reminds[:i], self.reminds[i+1:]...)
+
+	return okResponse(nil)
+}
+
+// Must be called with the mutex held.
+func (self *Server) findUser(id string) *slopher.User {
+	if id == "" {
+		id = self.Self.ID
+	}
+	for _, user := range self.users {
+		if user.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+func (self *Server) usersProfileGet(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	user := self.findUser(req.Args["user"])
+	if user == nil {
+		return ErrorResponse("user_not_found")
+	}
+	profile := user.Profile
+	if profile == nil {
+		profile = &slopher.UserProfile{}
+	}
+
+	return okResponse(map[string]interface{}{"profile": profile})
+}
+
+// Merges the changed fields into the user's profile by way of JSON.
+func (self *Server) usersProfileSet(req *Request) interface{} {
+	changes := make(map[string]interface{})
+	if profile := req.Args["profile"]; profile != "" {
+		if err := json.Unmarshal([]byte(profile), &changes); err != nil {
+			return ErrorResponse("invalid_profile")
+		}
+	}
+	if name := req.Args["name"]; name != "" {
+		changes[name] = req.Args["value"]
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	user := self.findUser(req.Args["user"])
+	if user == nil {
+		return ErrorResponse("user_not_found")
+	}
+	if user.Profile == nil {
+		user.Profile = &slopher.UserProfile{}
+	}
+
+	merged := make(map[string]interface{})
+	data, _ := json.Marshal(user.Profile)
+	json.Unmarshal(data, &merged)
+	for k, v := range changes {
+		merged[k] = v
+	}
+	data, _ = json.Marshal(merged)
+	profile := &slopher.UserProfile{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return ErrorResponse("invalid_profile")
+	}
+	user.Profile = profile
+
+	return okResponse(map[string]interface{}{"profile": profile})
+}
+
+func (self *Server) findChannel(id string) *slopher.Channel {
+	for _, channel := range self.channels {
+		if channel.ID == id {
+			return channel
+		}
+	}
+	return nil
+}
+
+func (self *Server) conversationsLeave(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	channel := self.findChannel(req.Args["channel"])
+	if channel == nil {
+		return ErrorResponse("channel_not_found")
+	}
+	channel.IsMember = false
+
+	return nil
+}
+
+// The seeded channels, groups and IMs, as conversations.* returns them.
+// Must be called with the mutex held.
+func (self *Server) conversations() []*slopher.Conversation {
+	convs := make([]*slopher.Conversation, 0)
+	for _, c := range self.channels {
+		convs = append(convs, &slopher.Conversation{
+			ID: c.ID, Name: c.Name, Created: c.Created, Creator: c.Creator,
+			IsChannel: true, IsMember: c.IsMember, IsArchived: c.IsArchived,
+			IsGeneral: c.IsGeneral, Members: c.Members,
+			NumMembers: len(c.Members), Purpose: c.Purpose, Topic: c.Topic,
+		})
+	}
+	for _, g := range self.groups {
+		convs = append(convs, &slopher.Conversation{
+			ID: g.ID, Name: g.Name, Created: g.Created, Creator: g.Creator,
+			IsChannel: true, IsPrivate: true, IsMember: true,
+			IsArchived: g.IsArchived, Members: g.Members,
+			NumMembers: len(g.Members), Purpose: g.Purpose, Topic: g.Topic,
+		})
+	}
+	for _, im := range self.ims {
+		convs = append(convs, &slopher.Conversation{
+			ID: im.ID, Created: im.Created, IsIM: true, IsOpen: im.IsOpen,
+			UserID: im.UserID,
+		})
+	}
+	return convs
+}
+
+func (self *Server) findConversation(id string) *slopher.Conversation {
+	for _, conv := range self.conversations() {
+		if conv.ID == id {
+			return conv
+		}
+	}
+	return nil
+}
+
+func (self *Server) conversationsJoin(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	channel := self.findChannel(req.Args["channel"])
+	if channel == nil {
+		return ErrorResponse("channel_not_found")
+	}
+	if !channel.IsMember {
+		channel.IsMember = true
+		channel.Members = append(channel.Members, self.Self.ID)
+	}
+
+	return okResponse(map[string]interface{}{
+		"channel": self.findConversation(channel.ID),
+	})
+}
+
+func (self *Server) conversationsInfo(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	conv := self.findConversation(req.Args["channel"])
+	if conv == nil {
+		return ErrorResponse("channel_not_found")
+	}
+
+	return okResponse(map[string]interface{}{"channel": conv})
+}
+
+// Filters by "types" but returns everything in one page.
+func (self *Server) conversationsList(req *Request) interface{} {
+	types := map[string]bool{"public_channel": true}
+	if req.Args["types"] != "" {
+		types = make(map[string]bool)
+		for _, t := range strings.Split(req.Args["types"], ",") {
+			types[t] = true
+		}
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	convs := make([]*slopher.Conversation, 0)
+	for _, conv := range self.conversations() {
+		switch {
+		case conv.IsIM && types["im"],
+			conv.IsPrivate && types["private_channel"],
+			conv.IsPublicChannel() && types["public_channel"]:
+			convs = append(convs, conv)
+		}
+	}
+
+	return okResponse(map[string]interface{}{"channels": convs})
+}
+
+func (self *Server) conversationsMembers(req *Request) interface{} {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	conv := self.findConversation(req.Args["channel"])
+	if conv == nil {
+		return ErrorResponse("channel_not_found")
+	}
+	members := conv.Members
+	if conv.IsIM {
+		members = []string{self.Self.ID, conv.UserID}
+	}
+
+	return okResponse(map[string]interface{}{"members": members})
+}
+
+func (self *Server) filesUpload(req *Request) interface{} {
+	file := &slopher.SharedFile{
+		ID:       self.nextID("F"),
+		Name:     req.Args["filename"],
+		Title:    req.Args["title"],
+		FileType: req.Args["filetype"],
+		UserID:   self.Self.ID,
+	}
+	if channels := req.Args["channels"]; channels != "" {
+		file.ChannelIDs = strings.Split(channels, ",")
+	}
+
+	self.mutex.Lock()
+	self.storeFile(file, req.File)
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{"file": file})
+}
+
+func (self *Server) filesGetUploadURLExternal(req *Request) interface{} {
+	file := &slopher.SharedFile{
+		ID:     self.nextID("F"),
+		Name:   req.Args["filename"],
+		UserID: self.Self.ID,
+	}
+
+	self.mutex.Lock()
+	self.files[file.ID] = file
+	self.mutex.Unlock()
+
+	return okResponse(map[string]interface{}{
+		"upload_url": self.srv.URL + "/upload/" + file.ID,
+		"file_id":    file.ID,
+	})
+}
+
+func (self *Server) filesCompleteUploadExternal(req *Request) interface{} {
+	var ext_files []slopher.ExternalFile
+	if err := json.Unmarshal([]byte(req.Args["files"]), &ext_files); err != nil {
+		return ErrorResponse("invalid_arguments")
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	files := make([]*slopher.SharedFile, 0, len(ext_files))
+	for _, ext_file := range ext_files {
+		file, ok := self.files[ext_file.ID]
+		if !ok {
+			return ErrorResponse("file_not_found")
+		}
+		file.Title = ext_file.Title
+		if channel := req.Args["channel_id"]; channel != "" {
+			file.ChannelIDs = append(file.ChannelIDs, channel)
+		}
+		files = append(files, file)
+	}
+
+	return okResponse(map[string]interface{}{"files": files})
+}